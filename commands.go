@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"glox/lox"
+)
+
+// command is a subcommand, run as "glox name args...".
+type command struct {
+	args string // argument synopsis for the usage message
+	desc string
+	run  func(args []string) int
+}
+
+func commands() map[string]command {
+	return map[string]command{
+		"compile": {"[-o file] script", "compile a script to bytecode that glox can run later", runCompile},
+		"disasm":  {"script", "print the bytecode of a script or compiled file", runDisasm},
+		"fmt":     {"[-w] [script...]", "reformat scripts, or standard input, in the canonical style", runFmt},
+		"parse":   {"script", "print the syntax tree of a script as S-expressions", runParse},
+		"tokens":  {"script", "print the tokens and comments of a script as JSON", runTokens},
+		"ast":     {"script", "print the syntax tree of a script as JSON", runAST},
+	}
+}
+
+// newFlagSet returns the flag set of the command name, whose usage message
+// shows args.
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s %s\n", os.Args[0], name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runCompile compiles a script and saves the bytecode, by default next to
+// the script with a .loxc extension.
+func runCompile(args []string) int {
+	fs := newFlagSet("compile", commands()["compile"].args)
+	out := fs.String("o", "", "output file (default: the script with a .loxc extension)")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return exitUsage
+	}
+	path := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(path, filepath.Ext(path)) + ".loxc"
+	}
+
+	content, err := openFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read file: %v\n", err)
+		return exitIOErr
+	}
+	prog, err := lox.New().Compile(path, string(content))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	var buf bytes.Buffer
+	if _, err := prog.WriteTo(&buf); err != nil {
+		fmt.Fprintf(os.Stderr, "write program: %v\n", err)
+		return exitIOErr
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "write program: %v\n", err)
+		return exitIOErr
+	}
+	return exitOK
+}
+
+// runDisasm prints the bytecode of a script, interleaved with its source,
+// or of a compiled file.
+func runDisasm(args []string) int {
+	fs := newFlagSet("disasm", commands()["disasm"].args)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return exitUsage
+	}
+	path := fs.Arg(0)
+
+	content, err := openFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read file: %v\n", err)
+		return exitIOErr
+	}
+	var prog *lox.Program
+	var source string
+	if lox.IsProgram(content) {
+		if prog, err = lox.ReadProgram(bytes.NewReader(content)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitStatic
+		}
+	} else {
+		source = string(content)
+		if prog, err = lox.New().Compile(path, source); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCode(err)
+		}
+	}
+	if err := prog.Disassemble(os.Stdout, source); err != nil {
+		fmt.Fprintf(os.Stderr, "write: %v\n", err)
+		return exitIOErr
+	}
+	return exitOK
+}
+
+// runFmt prints the scripts named in args reformatted, or rewrites them
+// with -w. Without scripts it formats standard input.
+func runFmt(args []string) int {
+	fs := newFlagSet("fmt", commands()["fmt"].args)
+	write := fs.Bool("w", false, "write the result to the script instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "fmt: -w needs a script to write to")
+			return exitUsage
+		}
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read input: %v\n", err)
+			return exitIOErr
+		}
+		out, err := lox.Format("", string(content))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCode(err)
+		}
+		fmt.Print(out)
+		return exitOK
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		content, err := openFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read file: %v\n", err)
+			code = exitIOErr
+			continue
+		}
+		out, err := lox.Format(path, string(content))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = exitCode(err)
+			continue
+		}
+		if !*write {
+			fmt.Print(out)
+		} else if out != string(content) {
+			if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "write file: %v\n", err)
+				code = exitIOErr
+			}
+		}
+	}
+	return code
+}
+
+// runParse prints each top-level statement of a script as an S-expression.
+func runParse(args []string) int {
+	fs := newFlagSet("parse", commands()["parse"].args)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return exitUsage
+	}
+	lex, code := scanFile(fs.Arg(0))
+	if code != exitOK {
+		return code
+	}
+	stmts, err := lox.NewParser(lex.Tokens).Parse()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitStatic
+	}
+	var p lox.AstPrinter
+	for _, s := range stmts {
+		fmt.Println(p.PrintStmt(s))
+	}
+	return exitOK
+}
+
+// runTokens prints the tokens and comments of a script as JSON.
+func runTokens(args []string) int {
+	fs := newFlagSet("tokens", commands()["tokens"].args)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return exitUsage
+	}
+	lex, code := scanFile(fs.Arg(0))
+	if code != exitOK {
+		return code
+	}
+	return printJSON(lox.MarshalTokens(lex))
+}
+
+// runAST prints the syntax tree of a script as JSON.
+func runAST(args []string) int {
+	fs := newFlagSet("ast", commands()["ast"].args)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return exitUsage
+	}
+	lex, code := scanFile(fs.Arg(0))
+	if code != exitOK {
+		return code
+	}
+	stmts, err := lox.NewParser(lex.Tokens).Parse()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitStatic
+	}
+	return printJSON(lox.MarshalAST(lex.File, stmts))
+}
+
+// scanFile reads and scans the script at path. On failure it reports the
+// error and returns the exit code.
+func scanFile(path string) (*lox.Lexer, int) {
+	content, err := openFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read file: %v\n", err)
+		return nil, exitIOErr
+	}
+	lex := &lox.Lexer{Source: string(content), Tokens: []lox.Token{}, File: path}
+	if err := lex.Scan(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, exitStatic
+	}
+	return lex, exitOK
+}
+
+func printJSON(data []byte, err error) int {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encode json: %v\n", err)
+		return exitRuntime
+	}
+	fmt.Println(string(data))
+	return exitOK
+}
@@ -0,0 +1,224 @@
+package lox
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AstPrinter renders syntax trees as S-expressions, like the AstPrinter of
+// the book, for debugging the parser:
+//
+//	var x = (1 + 2) * -y;  =>  (var x (* (group (+ 1 2)) (- y)))
+//
+// Statements print as (print e), (; e) for expression statements,
+// (block ...), (if cond then [else]), (while cond body [(incr e)]) and
+// so on; desugared for loops show up as the while loops they become.
+type AstPrinter struct{}
+
+// Print returns expr as an S-expression.
+func (p AstPrinter) Print(expr Expr) string {
+	s, _ := expr.Accept(p)
+	return s.(string)
+}
+
+// PrintStmt returns stmt as an S-expression.
+func (p AstPrinter) PrintStmt(stmt Stmt) string {
+	// VarStmt.Accept drops what the visitor returns.
+	if v, ok := stmt.(*VarStmt); ok {
+		var s any
+		if v.Init != nil {
+			s, _ = p.parenthesize("var", v.Name, v.Init)
+		} else {
+			s, _ = p.parenthesize("var", v.Name)
+		}
+		return s.(string)
+	}
+	s, _ := stmt.Accept(p)
+	return s.(string)
+}
+
+// parenthesize builds "(name parts...)" from strings, expressions,
+// statements and tokens.
+func (p AstPrinter) parenthesize(name string, parts ...any) (Value, error) {
+	var b strings.Builder
+	b.WriteString("(")
+	b.WriteString(name)
+	for _, part := range parts {
+		b.WriteString(" ")
+		switch part := part.(type) {
+		case string:
+			b.WriteString(part)
+		case Token:
+			b.WriteString(part.Lexeme)
+		case Expr:
+			b.WriteString(p.Print(part))
+		case Stmt:
+			b.WriteString(p.PrintStmt(part))
+		}
+	}
+	b.WriteString(")")
+	return b.String(), nil
+}
+
+// function lists params and body as parts of a function's S-expression.
+func (p AstPrinter) function(params []Token, body []Stmt) []any {
+	names := make([]string, len(params))
+	for j, param := range params {
+		names[j] = param.Lexeme
+	}
+	parts := []any{"(" + strings.Join(names, " ") + ")"}
+	for _, s := range body {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+func (p AstPrinter) visitAssignExpr(expr *AssignExpr) (Value, error) {
+	return p.parenthesize("=", expr.Name, expr.Value)
+}
+
+func (p AstPrinter) visitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+func (p AstPrinter) visitCallExpr(expr *CallExpr) (Value, error) {
+	parts := []any{expr.Callee}
+	for _, arg := range expr.Args {
+		parts = append(parts, arg)
+	}
+	return p.parenthesize("call", parts...)
+}
+
+func (p AstPrinter) visitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	return p.parenthesize("fun", p.function(expr.Params, expr.Body)...)
+}
+
+func (p AstPrinter) visitGetExpr(expr *GetExpr) (Value, error) {
+	return p.parenthesize(".", expr.Object, expr.Name)
+}
+
+func (p AstPrinter) visitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	return p.parenthesize("group", expr.Expr)
+}
+
+func (p AstPrinter) visitIndexGetExpr(expr *IndexGetExpr) (Value, error) {
+	return p.parenthesize("[]", expr.Object, expr.Index)
+}
+
+func (p AstPrinter) visitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	target, _ := p.parenthesize("[]", expr.Object, expr.Index)
+	return p.parenthesize("=", target, expr.Val)
+}
+
+func (p AstPrinter) visitListExpr(expr *ListExpr) (Value, error) {
+	parts := make([]any, len(expr.Elems))
+	for j, e := range expr.Elems {
+		parts[j] = e
+	}
+	return p.parenthesize("list", parts...)
+}
+
+func (p AstPrinter) visitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	if s, ok := expr.Value.(string); ok {
+		return strconv.Quote(s), nil
+	}
+	return Stringify(expr.Value), nil
+}
+
+func (p AstPrinter) visitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+func (p AstPrinter) visitMapExpr(expr *MapExpr) (Value, error) {
+	parts := make([]any, len(expr.Keys))
+	for j := range expr.Keys {
+		parts[j], _ = p.parenthesize(":", expr.Keys[j], expr.Vals[j])
+	}
+	return p.parenthesize("map", parts...)
+}
+
+func (p AstPrinter) visitSetExpr(expr *SetExpr) (Value, error) {
+	target, _ := p.parenthesize(".", expr.Object, expr.Name)
+	return p.parenthesize("=", target, expr.Val)
+}
+
+func (p AstPrinter) visitSuperExpr(expr *SuperExpr) (Value, error) {
+	return p.parenthesize("super", expr.Method)
+}
+
+func (p AstPrinter) visitThisExpr(expr *ThisExpr) (Value, error) {
+	return "this", nil
+}
+
+func (p AstPrinter) visitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	return p.parenthesize(expr.Operator.Lexeme, expr.Right)
+}
+
+func (p AstPrinter) visitVarExpr(expr *VarExpr) (Value, error) {
+	return expr.Name.Lexeme, nil
+}
+
+func (p AstPrinter) visitBlockStmt(stmt *BlockStmt) (Value, error) {
+	parts := make([]any, len(stmt.Stmts))
+	for j, s := range stmt.Stmts {
+		parts[j] = s
+	}
+	return p.parenthesize("block", parts...)
+}
+
+func (p AstPrinter) visitBreakStmt(stmt *BreakStmt) (Value, error) {
+	return "(break)", nil
+}
+
+func (p AstPrinter) visitClassStmt(stmt *ClassStmt) (Value, error) {
+	parts := []any{stmt.Name}
+	if stmt.Superclass != nil {
+		parts = append(parts, "<", stmt.Superclass.Name)
+	}
+	for _, m := range stmt.Methods {
+		parts = append(parts, m)
+	}
+	return p.parenthesize("class", parts...)
+}
+
+func (p AstPrinter) visitContinueStmt(stmt *ContinueStmt) (Value, error) {
+	return "(continue)", nil
+}
+
+func (p AstPrinter) visitExprStmt(stmt *ExprStmt) (Value, error) {
+	return p.parenthesize(";", stmt.Expr)
+}
+
+func (p AstPrinter) visitFunStmt(stmt *FunStmt) (Value, error) {
+	return p.parenthesize("fun", append([]any{stmt.Name}, p.function(stmt.Params, stmt.Body)...)...)
+}
+
+func (p AstPrinter) visitIfStmt(stmt *IfStmt) (Value, error) {
+	if stmt.Else != nil {
+		return p.parenthesize("if", stmt.Cond, stmt.Then, stmt.Else)
+	}
+	return p.parenthesize("if", stmt.Cond, stmt.Then)
+}
+
+func (p AstPrinter) visitPrintStmt(stmt *PrintStmt) (Value, error) {
+	return p.parenthesize("print", stmt.Expr)
+}
+
+func (p AstPrinter) visitRetStmt(stmt *RetStmt) (Value, error) {
+	if stmt.Val != nil {
+		return p.parenthesize("return", stmt.Val)
+	}
+	return "(return)", nil
+}
+
+func (p AstPrinter) visitVarStmt(stmt *VarStmt) error {
+	return nil
+}
+
+func (p AstPrinter) visitWhileStmt(stmt *WhileStmt) (Value, error) {
+	if stmt.Incr != nil {
+		incr, _ := p.parenthesize("incr", stmt.Incr)
+		return p.parenthesize("while", stmt.Cond, stmt.Body, incr)
+	}
+	return p.parenthesize("while", stmt.Cond, stmt.Body)
+}
@@ -0,0 +1,223 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Bind wraps the Go function fn as a NativeFunction called name. The
+// arity is fn's number of parameters; variadic functions are not
+// supported.
+//
+// Parameters may be bools, strings, any integer or float kind, or an
+// interface type such as any, in which case the Lox value is passed as
+// is. Results follow the same rules, and an interface result must hold one
+// of those kinds or a Lox value. fn may return nothing, one value, an
+// error, or a value and an error; a non-nil error becomes a runtime error
+// in the script.
+func Bind(name string, fn any) (*NativeFunction, error) {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("bind %s: want a func, got: nil", name)
+	}
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("bind %s: want a func, got: %s", name, t)
+	}
+	if v.IsNil() {
+		return nil, fmt.Errorf("bind %s: nil %s", name, t)
+	}
+	if t.IsVariadic() {
+		return nil, fmt.Errorf("bind %s: variadic functions are not supported", name)
+	}
+	for j := 0; j < t.NumIn(); j++ {
+		if !bindable(t.In(j)) {
+			return nil, fmt.Errorf("bind %s: unsupported parameter type %s", name, t.In(j))
+		}
+	}
+	if err := checkResults(t); err != nil {
+		return nil, fmt.Errorf("bind %s: %v", name, err)
+	}
+
+	call := func(_ *Interpreter, args []Value) (Value, error) {
+		in := make([]reflect.Value, len(args))
+		for j, arg := range args {
+			rv, err := toGo(arg, t.In(j))
+			if err != nil {
+				return nil, fmt.Errorf("%s: argument %d: %v", name, j+1, err)
+			}
+			in[j] = rv
+		}
+		return fromGoResults(name, v.Call(in))
+	}
+	return NewNativeFunction(name, t.NumIn(), call), nil
+}
+
+// DefineFunc binds fn with Bind and defines it as the global name.
+func (i *Interpreter) DefineFunc(name string, fn any) error {
+	native, err := Bind(name, fn)
+	if err != nil {
+		return err
+	}
+	i.Define(name, native)
+	return nil
+}
+
+// WithFunc defines a Go function, see DefineFunc. It panics if fn can not
+// be bound, as that is a programming error in the host.
+func WithFunc(name string, fn any) Option {
+	native, err := Bind(name, fn)
+	if err != nil {
+		panic(err)
+	}
+	return func(i *Interpreter) {
+		i.Define(name, native)
+	}
+}
+
+func bindable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func checkResults(t reflect.Type) error {
+	switch t.NumOut() {
+	case 0:
+		return nil
+	case 1:
+		if t.Out(0) == errorType || bindable(t.Out(0)) {
+			return nil
+		}
+		return fmt.Errorf("unsupported result type %s", t.Out(0))
+	case 2:
+		if !bindable(t.Out(0)) {
+			return fmt.Errorf("unsupported result type %s", t.Out(0))
+		}
+		if t.Out(1) != errorType {
+			return fmt.Errorf("second result must be an error, got: %s", t.Out(1))
+		}
+		return nil
+	}
+	return fmt.Errorf("too many results: %d", t.NumOut())
+}
+
+// toGo converts a Lox value to a Go value of type t.
+func toGo(v Value, t reflect.Type) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.Interface:
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.Type().Implements(t) {
+			return reflect.Value{}, fmt.Errorf("%s does not implement %s", typeName(v), t)
+		}
+		return rv.Convert(t), nil
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("want bool, got: %s", typeName(v))
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("want string, got: %s", typeName(v))
+		}
+		return reflect.ValueOf(s).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := v.(float64)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("want number, got: %s", typeName(v))
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+	}
+
+	// Integer kinds.
+	f, ok := v.(float64)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("want number, got: %s", typeName(v))
+	}
+	if f != math.Trunc(f) || math.IsInf(f, 0) {
+		return reflect.Value{}, fmt.Errorf("want integer, got: %s", Stringify(f))
+	}
+	// Check the range before converting, as converting a float64 out of
+	// range of the integer type wraps around.
+	rv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f < 0 || f >= math.Ldexp(1, t.Bits()) {
+			return reflect.Value{}, fmt.Errorf("%s overflows %s", Stringify(f), t)
+		}
+		rv.SetUint(uint64(f))
+	default:
+		if max := math.Ldexp(1, t.Bits()-1); f < -max || f >= max {
+			return reflect.Value{}, fmt.Errorf("%s overflows %s", Stringify(f), t)
+		}
+		rv.SetInt(int64(f))
+	}
+	return rv, nil
+}
+
+// fromGo converts a Go result back to a Lox value.
+func fromGo(rv reflect.Value) (Value, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Interface, reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Kind() == reflect.Interface {
+			return fromGo(rv.Elem())
+		}
+	}
+	// Anything else must be a Lox value, such as a list passed in through
+	// an interface parameter.
+	switch v := rv.Interface().(type) {
+	case Callable, *Instance, *List, *Map:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is not a Lox value", rv.Type())
+}
+
+// fromGoResults converts the results of the function bound as name.
+func fromGoResults(name string, out []reflect.Value) (Value, error) {
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		if out[0].Type() == errorType {
+			if out[0].IsNil() {
+				return nil, nil
+			}
+			return nil, out[0].Interface().(error)
+		}
+	case 2:
+		if !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+	}
+	v, err := fromGo(out[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: result: %v", name, err)
+	}
+	return v, nil
+}
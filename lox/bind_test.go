@@ -0,0 +1,123 @@
+package lox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"glox/lox"
+)
+
+var backends = []lox.Backend{lox.TreeWalker, lox.BytecodeVM}
+
+// runSource runs src on backend with opts and returns what it printed.
+func runSource(backend lox.Backend, src string, opts ...lox.Option) (string, error) {
+	var out bytes.Buffer
+	opts = append([]lox.Option{lox.WithBackend(backend), lox.WithStdout(&out)}, opts...)
+	_, err := lox.New(opts...).RunFile(context.Background(), "test.lox", src)
+	return out.String(), err
+}
+
+func TestBind(t *testing.T) {
+	type S struct{ X int }
+	errSentinel := errors.New("sentinel")
+	funcs := []lox.Option{
+		lox.WithFunc("add", func(a, b int) int { return a + b }),
+		lox.WithFunc("i8", func(n int8) int8 { return n }),
+		lox.WithFunc("u8", func(n uint8) uint8 { return n }),
+		lox.WithFunc("i64", func(n int64) int64 { return n }),
+		lox.WithFunc("u64", func(n uint64) uint64 { return n }),
+		lox.WithFunc("half", func(f float32) float32 { return f / 2 }),
+		lox.WithFunc("shout", func(s string, loud bool) string {
+			if loud {
+				return strings.ToUpper(s)
+			}
+			return s
+		}),
+		lox.WithFunc("same", func(v any) any { return v }),
+		lox.WithFunc("slice", func() any { return []int{1, 2} }),
+		lox.WithFunc("object", func() any { return S{1} }),
+		lox.WithFunc("nilList", func() any { return (*lox.List)(nil) }),
+		lox.WithFunc("list", func() any { return lox.NewList([]lox.Value{1.0, "a"}) }),
+		lox.WithFunc("fail", func() (int, error) { return 0, errSentinel }),
+		lox.WithFunc("check", func(ok bool) error {
+			if !ok {
+				return errSentinel
+			}
+			return nil
+		}),
+	}
+
+	tests := []struct {
+		src, want, err string
+	}{
+		{src: `print add(2, 3);`, want: "5"},
+		{src: `print i8(-128);`, want: "-128"},
+		{src: `print i8(128);`, err: "i8: argument 1: 128 overflows int8"},
+		{src: `print u8(255);`, want: "255"},
+		{src: `print u8(-1);`, err: "u8: argument 1: -1 overflows uint8"},
+		{src: `print i64(-9007199254740992);`, want: "-9007199254740992"},
+		{src: `print i64(pow(2, 63));`, err: "overflows int64"},
+		{src: `print i64(pow(10, 300));`, err: "overflows int64"},
+		{src: `print i64(-pow(2, 63)) == -pow(2, 63);`, want: "true"},
+		{src: `print u64(pow(2, 64));`, err: "overflows uint64"},
+		{src: `print u64(pow(2, 63)) == pow(2, 63);`, want: "true"},
+		{src: `print add(1.5, 1);`, err: "add: argument 1: want integer, got: 1.5"},
+		{src: `print add("1", 1);`, err: "add: argument 1: want number, got: string"},
+		{src: `print half(3);`, want: "1.5"},
+		{src: `print shout("hi", true);`, want: "HI"},
+		{src: `print shout("hi", nil);`, err: "shout: argument 2: want bool, got: nil"},
+		{src: `var l = [1]; print same(l) == l;`, want: "true"},
+		{src: `print same(nil);`, want: "nil"},
+		{src: `print slice();`, err: "slice: result: []int is not a Lox value"},
+		{src: `print type(object());`, err: "object: result: lox_test.S is not a Lox value"},
+		{src: `print nilList();`, want: "nil"},
+		{src: `print list();`, want: `[1, "a"]`},
+		{src: `fail();`, err: "sentinel"},
+		{src: `check(true); print "ok";`, want: "ok"},
+		{src: `check(false);`, err: "sentinel"},
+	}
+	for _, backend := range backends {
+		for _, tt := range tests {
+			out, err := runSource(backend, tt.src, funcs...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("%v: %s: error = %v, want one containing %q", backend, tt.src, err, tt.err)
+				}
+				if tt.err == "sentinel" && !errors.Is(err, errSentinel) {
+					t.Errorf("%v: %s: error does not wrap the Go error", backend, tt.src)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%v: %s: %v", backend, tt.src, err)
+			} else if got := strings.TrimSuffix(out, "\n"); got != tt.want {
+				t.Errorf("%v: %s: printed %q, want %q", backend, tt.src, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestBindRejects(t *testing.T) {
+	tests := []struct {
+		fn   any
+		want string
+	}{
+		{42, "want a func, got: int"},
+		{nil, "want a func, got: nil"},
+		{(func(int) int)(nil), "nil func(int) int"},
+		{func(xs ...int) {}, "variadic functions are not supported"},
+		{func(m map[string]int) {}, "unsupported parameter type map[string]int"},
+		{func() []int { return nil }, "unsupported result type []int"},
+		{func() (int, int) { return 0, 0 }, "second result must be an error, got: int"},
+		{func() (int, int, error) { return 0, 0, nil }, "too many results: 3"},
+	}
+	for _, tt := range tests {
+		_, err := lox.Bind("f", tt.fn)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Bind(%T) error = %v, want one containing %q", tt.fn, err, tt.want)
+		}
+	}
+}
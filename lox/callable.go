@@ -0,0 +1,6 @@
+package lox
+
+type Callable interface {
+	Call(interp *Interpreter, args []Value) (Value, error)
+	Arity() int
+}
@@ -0,0 +1,203 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Opcode is a VM instruction. Operands follow the opcode in the code
+// stream; their widths are given next to each opcode.
+type Opcode byte
+
+const (
+	OpConstant     Opcode = iota // u16 constant index
+	OpNil                        //
+	OpTrue                       //
+	OpFalse                      //
+	OpPop                        //
+	OpGetLocal                   // u8 stack slot
+	OpSetLocal                   // u8 stack slot
+	OpGetGlobal                  // u16 name constant
+	OpDefineGlobal               // u16 name constant
+	OpSetGlobal                  // u16 name constant
+	OpGetUpvalue                 // u8 upvalue index
+	OpSetUpvalue                 // u8 upvalue index
+	OpGetProperty                // u16 name constant
+	OpSetProperty                // u16 name constant
+	OpGetSuper                   // u16 name constant
+	OpEqual                      //
+	OpGreater                    //
+	OpGreaterEqual               //
+	OpLess                       //
+	OpLessEqual                  //
+	OpAdd                        //
+	OpSubtract                   //
+	OpMultiply                   //
+	OpDivide                     //
+	OpNot                        //
+	OpNegate                     //
+	OpPrint                      //
+	OpJump                       // u16 forward offset
+	OpJumpIfFalse                // u16 forward offset
+	OpLoop                       // u16 backward offset
+	OpCall                       // u8 argument count
+	OpClosure                    // u16 function constant, then u8 isLocal and u8 index per upvalue
+	OpCloseUpvalue               //
+	OpReturn                     //
+	OpClass                      // u16 name constant
+	OpInherit                    //
+	OpMethod                     // u16 name constant
+	OpList                       // u16 element count
+	OpMap                        // u16 entry count
+	OpIndexGet                   //
+	OpIndexSet                   //
+)
+
+// operand is the kind of operand that follows an opcode.
+type operand int
+
+const (
+	noOperand      operand = iota
+	constOperand           // u16 index of a number or string constant
+	nameOperand            // u16 index of a string constant
+	slotOperand            // u8 stack slot
+	upvalueOperand         // u8 upvalue index
+	jumpOperand            // u16 forward offset
+	loopOperand            // u16 backward offset
+	argcOperand            // u8 count
+	countOperand           // u16 count
+	closureOperand         // u16 function constant and its upvalues
+)
+
+// width returns the number of bytes the operand takes, not counting the
+// upvalues of a closureOperand.
+func (k operand) width() int {
+	switch k {
+	case noOperand:
+		return 0
+	case slotOperand, upvalueOperand, argcOperand:
+		return 1
+	}
+	return 2
+}
+
+var opcodes = [...]struct {
+	name    string
+	operand operand
+}{
+	OpConstant:     {"OP_CONSTANT", constOperand},
+	OpNil:          {"OP_NIL", noOperand},
+	OpTrue:         {"OP_TRUE", noOperand},
+	OpFalse:        {"OP_FALSE", noOperand},
+	OpPop:          {"OP_POP", noOperand},
+	OpGetLocal:     {"OP_GET_LOCAL", slotOperand},
+	OpSetLocal:     {"OP_SET_LOCAL", slotOperand},
+	OpGetGlobal:    {"OP_GET_GLOBAL", nameOperand},
+	OpDefineGlobal: {"OP_DEFINE_GLOBAL", nameOperand},
+	OpSetGlobal:    {"OP_SET_GLOBAL", nameOperand},
+	OpGetUpvalue:   {"OP_GET_UPVALUE", upvalueOperand},
+	OpSetUpvalue:   {"OP_SET_UPVALUE", upvalueOperand},
+	OpGetProperty:  {"OP_GET_PROPERTY", nameOperand},
+	OpSetProperty:  {"OP_SET_PROPERTY", nameOperand},
+	OpGetSuper:     {"OP_GET_SUPER", nameOperand},
+	OpEqual:        {"OP_EQUAL", noOperand},
+	OpGreater:      {"OP_GREATER", noOperand},
+	OpGreaterEqual: {"OP_GREATER_EQUAL", noOperand},
+	OpLess:         {"OP_LESS", noOperand},
+	OpLessEqual:    {"OP_LESS_EQUAL", noOperand},
+	OpAdd:          {"OP_ADD", noOperand},
+	OpSubtract:     {"OP_SUBTRACT", noOperand},
+	OpMultiply:     {"OP_MULTIPLY", noOperand},
+	OpDivide:       {"OP_DIVIDE", noOperand},
+	OpNot:          {"OP_NOT", noOperand},
+	OpNegate:       {"OP_NEGATE", noOperand},
+	OpPrint:        {"OP_PRINT", noOperand},
+	OpJump:         {"OP_JUMP", jumpOperand},
+	OpJumpIfFalse:  {"OP_JUMP_IF_FALSE", jumpOperand},
+	OpLoop:         {"OP_LOOP", loopOperand},
+	OpCall:         {"OP_CALL", argcOperand},
+	OpClosure:      {"OP_CLOSURE", closureOperand},
+	OpCloseUpvalue: {"OP_CLOSE_UPVALUE", noOperand},
+	OpReturn:       {"OP_RETURN", noOperand},
+	OpClass:        {"OP_CLASS", nameOperand},
+	OpInherit:      {"OP_INHERIT", noOperand},
+	OpMethod:       {"OP_METHOD", nameOperand},
+	OpList:         {"OP_LIST", countOperand},
+	OpMap:          {"OP_MAP", countOperand},
+	OpIndexGet:     {"OP_INDEX_GET", noOperand},
+	OpIndexSet:     {"OP_INDEX_SET", noOperand},
+}
+
+func (op Opcode) String() string {
+	if int(op) < len(opcodes) {
+		return opcodes[op].name
+	}
+	return fmt.Sprintf("OP_UNKNOWN(%d)", byte(op))
+}
+
+// Chunk is the compiled code of one function.
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+
+	// lines maps code offsets to source positions. It holds an entry for
+	// every instruction whose position differs from the one before it,
+	// in code order.
+	lines []lineEntry
+}
+
+type lineEntry struct {
+	offset int
+	line   int
+	column int
+}
+
+func (c *Chunk) writeOp(op Opcode, line, column int) {
+	n := len(c.lines)
+	if n == 0 || c.lines[n-1].line != line || c.lines[n-1].column != column {
+		c.lines = append(c.lines, lineEntry{offset: len(c.Code), line: line, column: column})
+	}
+	c.Code = append(c.Code, byte(op))
+}
+
+func (c *Chunk) writeByte(b byte) {
+	c.Code = append(c.Code, b)
+}
+
+func (c *Chunk) writeShort(v int) {
+	c.Code = append(c.Code, byte(v>>8), byte(v))
+}
+
+func (c *Chunk) readShort(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
+
+// position returns the source line and column of the instruction at
+// offset.
+func (c *Chunk) position(offset int) (line, column int) {
+	j := sort.Search(len(c.lines), func(j int) bool { return c.lines[j].offset > offset })
+	if j == 0 {
+		return 0, 0
+	}
+	e := c.lines[j-1]
+	return e.line, e.column
+}
+
+// funProto is a compiled function. The VM wraps it in a closure to call it.
+type funProto struct {
+	// name is empty for the top-level script and anonymous functions.
+	name     string
+	arity    int
+	upvalues int
+	chunk    Chunk
+
+	// file names the source the function was compiled from.
+	file string
+}
+
+func (p *funProto) String() string {
+	if p.name == "" {
+		return "<fn>"
+	}
+	return "<fn " + p.name + ">"
+}
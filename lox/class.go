@@ -0,0 +1,85 @@
+package lox
+
+// LoxClass is the runtime representation of a class declaration. Calling a
+// class creates a new Instance and runs its initializer, if any.
+type LoxClass struct {
+	Name       string
+	superclass *LoxClass
+	methods    map[string]method
+}
+
+// method is a function declared in a class body: a *Function when run by
+// the tree-walker, a *closure when run by the VM.
+type method interface {
+	Callable
+	bind(inst *Instance) Callable
+}
+
+func NewLoxClass(name string, superclass *LoxClass) *LoxClass {
+	return &LoxClass{Name: name, superclass: superclass, methods: make(map[string]method)}
+}
+
+// findMethod looks up name on the class and then up the superclass chain.
+func (c *LoxClass) findMethod(name string) method {
+	if m, ok := c.methods[name]; ok {
+		return m
+	}
+	if c.superclass != nil {
+		return c.superclass.findMethod(name)
+	}
+	return nil
+}
+
+func (c *LoxClass) Call(interp *Interpreter, args []Value) (Value, error) {
+	inst := NewInstance(c)
+	if init := c.findMethod("init"); init != nil {
+		if _, err := init.bind(inst).Call(interp, args); err != nil {
+			return nil, err
+		}
+	}
+	return inst, nil
+}
+
+func (c *LoxClass) Arity() int {
+	if init := c.findMethod("init"); init != nil {
+		return init.Arity()
+	}
+	return 0
+}
+
+func (c *LoxClass) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	class  *LoxClass
+	fields map[string]Value
+}
+
+func NewInstance(class *LoxClass) *Instance {
+	return &Instance{class: class, fields: make(map[string]Value)}
+}
+
+// Get returns the field called name, or a method bound to the instance.
+// Fields shadow methods.
+func (in *Instance) Get(name Token) (Value, bool) {
+	return in.get(name.Lexeme)
+}
+
+func (in *Instance) get(name string) (Value, bool) {
+	if v, ok := in.fields[name]; ok {
+		return v, true
+	}
+	if m := in.class.findMethod(name); m != nil {
+		return m.bind(in), true
+	}
+	return nil, false
+}
+
+func (in *Instance) Set(name Token, val Value) {
+	in.fields[name.Lexeme] = val
+}
+
+func (in *Instance) String() string {
+	return in.class.Name + " instance"
+}
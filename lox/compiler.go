@@ -0,0 +1,787 @@
+package lox
+
+import "fmt"
+
+// Limits imposed by the operand widths of the bytecode.
+const (
+	maxLocals    = 256
+	maxUpvalues  = 256
+	maxConstants = 1 << 16
+	maxShort     = 1<<16 - 1
+)
+
+// compiler turns the statements of one function into bytecode. Nested
+// functions get their own compiler, linked through enclosing, so that
+// variables can be resolved to locals, upvalues or globals.
+//
+// The resolver has already checked the program, so the compiler only
+// reports errors for exceeding the limits of the bytecode format.
+type compiler struct {
+	enclosing *compiler
+	proto     *funProto
+	kind      funType
+
+	// locals mirrors the VM stack slots of the function's frame.
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+
+	// loops are the loops enclosing the code being compiled, innermost
+	// last.
+	loops []*loopInfo
+
+	// constants indexes the constant pool by value, so that names used
+	// many times are stored once.
+	constants map[Value]int
+}
+
+type local struct {
+	name string
+
+	// depth is the scope depth the local was declared at, or -1 while
+	// its initializer is being compiled.
+	depth int
+
+	// captured is set when a closure refers to the local, so that it is
+	// moved off the stack when it goes out of scope.
+	captured bool
+}
+
+type upvalueRef struct {
+	// index is a stack slot of the enclosing function when isLocal is
+	// set, and one of its upvalues otherwise.
+	index   int
+	isLocal bool
+}
+
+type loopInfo struct {
+	// depth is the scope depth outside the loop body.
+	depth     int
+	breaks    []int
+	continues []int
+}
+
+// compile compiles a resolved program into the function the VM runs as
+// the top-level script. The script returns the value of its last
+// statement if that is an expression statement, like interpret.
+func compile(stmts []Stmt, file string) (*funProto, error) {
+	c := newCompiler(nil, funTypeNone, "", file)
+	for j, s := range stmts {
+		if es, ok := s.(*ExprStmt); ok && j == len(stmts)-1 {
+			if err := c.expr(es.Expr); err != nil {
+				return nil, err
+			}
+			c.op(es.Semicolon, OpReturn)
+			return c.proto, nil
+		}
+		if err := c.stmt(s); err != nil {
+			return nil, err
+		}
+	}
+	c.emitReturn(Token{})
+	return c.proto, nil
+}
+
+func newCompiler(enclosing *compiler, kind funType, name, file string) *compiler {
+	c := &compiler{
+		enclosing: enclosing,
+		proto:     &funProto{name: name, file: file},
+		kind:      kind,
+		constants: make(map[Value]int),
+	}
+	// Slot 0 holds the function being called, or the receiver in methods.
+	slot0 := ""
+	if kind == funTypeMethod || kind == funTypeInitializer {
+		slot0 = "this"
+	}
+	c.locals = append(c.locals, local{name: slot0})
+	return c
+}
+
+func (c *compiler) chunk() *Chunk {
+	return &c.proto.chunk
+}
+
+func (c *compiler) expr(e Expr) error {
+	_, err := e.Accept(c)
+	return err
+}
+
+func (c *compiler) stmt(s Stmt) error {
+	_, err := s.Accept(c)
+	return err
+}
+
+func (c *compiler) error(tok Token, msg string) error {
+	return fmt.Errorf("[%s] compile error at '%s': %s", tok.Pos(), tok.Lexeme, msg)
+}
+
+// op emits an instruction without operands, positioned at tok.
+func (c *compiler) op(tok Token, op Opcode) {
+	c.chunk().writeOp(op, tok.Line, tok.Column)
+}
+
+func (c *compiler) opByte(tok Token, op Opcode, b int) {
+	c.op(tok, op)
+	c.chunk().writeByte(byte(b))
+}
+
+func (c *compiler) opShort(tok Token, op Opcode, v int) {
+	c.op(tok, op)
+	c.chunk().writeShort(v)
+}
+
+// constant adds v to the constant pool and returns its index.
+func (c *compiler) constant(tok Token, v Value) (int, error) {
+	if j, ok := c.constants[v]; ok {
+		return j, nil
+	}
+	if len(c.chunk().Constants) >= maxConstants {
+		return 0, c.error(tok, "too many constants in one function")
+	}
+	c.chunk().Constants = append(c.chunk().Constants, v)
+	j := len(c.chunk().Constants) - 1
+	c.constants[v] = j
+	return j, nil
+}
+
+// name adds the lexeme of tok to the constant pool.
+func (c *compiler) name(tok Token) (int, error) {
+	return c.constant(tok, tok.Lexeme)
+}
+
+// jump emits a jump with a placeholder offset and returns the position of
+// the offset for patchJump.
+func (c *compiler) jump(tok Token, op Opcode) int {
+	c.opShort(tok, op, 0xffff)
+	return len(c.chunk().Code) - 2
+}
+
+// patchJump points the jump whose offset is at to the next instruction.
+func (c *compiler) patchJump(tok Token, at int) error {
+	dist := len(c.chunk().Code) - at - 2
+	if dist > maxShort {
+		return c.error(tok, "too much code to jump over")
+	}
+	c.chunk().Code[at] = byte(dist >> 8)
+	c.chunk().Code[at+1] = byte(dist)
+	return nil
+}
+
+// loop emits a jump back to start.
+func (c *compiler) loop(tok Token, start int) error {
+	c.op(tok, OpLoop)
+	dist := len(c.chunk().Code) + 2 - start
+	if dist > maxShort {
+		return c.error(tok, "loop body too large")
+	}
+	c.chunk().writeShort(dist)
+	return nil
+}
+
+func (c *compiler) emitReturn(tok Token) {
+	if c.kind == funTypeInitializer {
+		c.opByte(tok, OpGetLocal, 0)
+	} else {
+		c.op(tok, OpNil)
+	}
+	c.op(tok, OpReturn)
+}
+
+func (c *compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *compiler) endScope(tok Token) {
+	c.scopeDepth--
+	c.popLocals(tok, c.scopeDepth)
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+// popLocals emits code discarding the locals deeper than depth, without
+// forgetting them. break and continue use it to leave scopes early.
+func (c *compiler) popLocals(tok Token, depth int) {
+	for j := len(c.locals) - 1; j >= 0 && c.locals[j].depth > depth; j-- {
+		if c.locals[j].captured {
+			c.op(tok, OpCloseUpvalue)
+		} else {
+			c.op(tok, OpPop)
+		}
+	}
+}
+
+// declare adds a local for name, unless it is declared at the top level
+// where variables are globals.
+func (c *compiler) declare(name Token) error {
+	if c.scopeDepth == 0 {
+		return nil
+	}
+	if len(c.locals) >= maxLocals {
+		return c.error(name, "too many local variables in function")
+	}
+	c.locals = append(c.locals, local{name: name.Lexeme, depth: -1})
+	return nil
+}
+
+// define makes the variable declared last available, after its
+// initializer has been compiled.
+func (c *compiler) define(name Token) error {
+	if c.scopeDepth > 0 {
+		c.locals[len(c.locals)-1].depth = c.scopeDepth
+		return nil
+	}
+	j, err := c.name(name)
+	if err != nil {
+		return err
+	}
+	c.opShort(name, OpDefineGlobal, j)
+	return nil
+}
+
+func (c *compiler) resolveLocal(name string) int {
+	for j := len(c.locals) - 1; j >= 0; j-- {
+		if c.locals[j].name == name {
+			return j
+		}
+	}
+	return -1
+}
+
+func (c *compiler) resolveUpvalue(tok Token) (int, error) {
+	if c.enclosing == nil {
+		return -1, nil
+	}
+	if slot := c.enclosing.resolveLocal(tok.Lexeme); slot >= 0 {
+		c.enclosing.locals[slot].captured = true
+		return c.addUpvalue(tok, slot, true)
+	}
+	j, err := c.enclosing.resolveUpvalue(tok)
+	if err != nil || j < 0 {
+		return j, err
+	}
+	return c.addUpvalue(tok, j, false)
+}
+
+func (c *compiler) addUpvalue(tok Token, index int, isLocal bool) (int, error) {
+	ref := upvalueRef{index: index, isLocal: isLocal}
+	for j, uv := range c.upvalues {
+		if uv == ref {
+			return j, nil
+		}
+	}
+	if len(c.upvalues) >= maxUpvalues {
+		return 0, c.error(tok, "too many closure variables in function")
+	}
+	c.upvalues = append(c.upvalues, ref)
+	return len(c.upvalues) - 1, nil
+}
+
+// variable emits code to read, or with set to write, the variable name.
+func (c *compiler) variable(name Token, set bool) error {
+	if slot := c.resolveLocal(name.Lexeme); slot >= 0 {
+		if set {
+			c.opByte(name, OpSetLocal, slot)
+		} else {
+			c.opByte(name, OpGetLocal, slot)
+		}
+		return nil
+	}
+	j, err := c.resolveUpvalue(name)
+	if err != nil {
+		return err
+	}
+	if j >= 0 {
+		if set {
+			c.opByte(name, OpSetUpvalue, j)
+		} else {
+			c.opByte(name, OpGetUpvalue, j)
+		}
+		return nil
+	}
+	k, err := c.name(name)
+	if err != nil {
+		return err
+	}
+	if set {
+		c.opShort(name, OpSetGlobal, k)
+	} else {
+		c.opShort(name, OpGetGlobal, k)
+	}
+	return nil
+}
+
+// function compiles a function body and emits the closure for it. pos is
+// where the function is declared.
+func (c *compiler) function(pos Token, kind funType, name string, params []Token, body []Stmt, rbrace Token) error {
+	fc := newCompiler(c, kind, name, c.proto.file)
+	fc.beginScope()
+	for _, p := range params {
+		if err := fc.declare(p); err != nil {
+			return err
+		}
+		if err := fc.define(p); err != nil {
+			return err
+		}
+	}
+	fc.proto.arity = len(params)
+	for _, s := range body {
+		if err := fc.stmt(s); err != nil {
+			return err
+		}
+	}
+	fc.emitReturn(rbrace)
+	fc.proto.upvalues = len(fc.upvalues)
+
+	j, err := c.constant(pos, fc.proto)
+	if err != nil {
+		return err
+	}
+	c.opShort(pos, OpClosure, j)
+	for _, uv := range fc.upvalues {
+		isLocal := 0
+		if uv.isLocal {
+			isLocal = 1
+		}
+		c.chunk().writeByte(byte(isLocal))
+		c.chunk().writeByte(byte(uv.index))
+	}
+	return nil
+}
+
+func (c *compiler) visitAssignExpr(expr *AssignExpr) (Value, error) {
+	if err := c.expr(expr.Value); err != nil {
+		return nil, err
+	}
+	return nil, c.variable(expr.Name, true)
+}
+
+func (c *compiler) visitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	if err := c.expr(expr.Left); err != nil {
+		return nil, err
+	}
+	if err := c.expr(expr.Right); err != nil {
+		return nil, err
+	}
+	op := expr.Operator
+	switch op.Type {
+	case BangEqual:
+		c.op(op, OpEqual)
+		c.op(op, OpNot)
+	case EqualEqual:
+		c.op(op, OpEqual)
+	case Greater:
+		c.op(op, OpGreater)
+	case GreaterEqual:
+		c.op(op, OpGreaterEqual)
+	case Less:
+		c.op(op, OpLess)
+	case LessEqual:
+		c.op(op, OpLessEqual)
+	case Plus:
+		c.op(op, OpAdd)
+	case Minus:
+		c.op(op, OpSubtract)
+	case Star:
+		c.op(op, OpMultiply)
+	case Slash:
+		c.op(op, OpDivide)
+	default:
+		return nil, c.error(op, "unknown binary operator")
+	}
+	return nil, nil
+}
+
+func (c *compiler) visitCallExpr(expr *CallExpr) (Value, error) {
+	if err := c.expr(expr.Callee); err != nil {
+		return nil, err
+	}
+	for _, arg := range expr.Args {
+		if err := c.expr(arg); err != nil {
+			return nil, err
+		}
+	}
+	c.opByte(expr.Paren, OpCall, len(expr.Args))
+	return nil, nil
+}
+
+func (c *compiler) visitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	return nil, c.function(expr.Keyword, funTypeFunction, "", expr.Params, expr.Body, expr.RBrace)
+}
+
+func (c *compiler) visitGetExpr(expr *GetExpr) (Value, error) {
+	if err := c.expr(expr.Object); err != nil {
+		return nil, err
+	}
+	j, err := c.name(expr.Name)
+	if err != nil {
+		return nil, err
+	}
+	c.opShort(expr.Name, OpGetProperty, j)
+	return nil, nil
+}
+
+func (c *compiler) visitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	return nil, c.expr(expr.Expr)
+}
+
+func (c *compiler) visitIndexGetExpr(expr *IndexGetExpr) (Value, error) {
+	if err := c.expr(expr.Object); err != nil {
+		return nil, err
+	}
+	if err := c.expr(expr.Index); err != nil {
+		return nil, err
+	}
+	c.op(expr.Bracket, OpIndexGet)
+	return nil, nil
+}
+
+func (c *compiler) visitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	if err := c.expr(expr.Object); err != nil {
+		return nil, err
+	}
+	if err := c.expr(expr.Index); err != nil {
+		return nil, err
+	}
+	if err := c.expr(expr.Val); err != nil {
+		return nil, err
+	}
+	c.op(expr.Bracket, OpIndexSet)
+	return nil, nil
+}
+
+func (c *compiler) visitListExpr(expr *ListExpr) (Value, error) {
+	if len(expr.Elems) > maxShort {
+		return nil, c.error(expr.LBracket, "too many elements in list literal")
+	}
+	for _, e := range expr.Elems {
+		if err := c.expr(e); err != nil {
+			return nil, err
+		}
+	}
+	c.opShort(expr.LBracket, OpList, len(expr.Elems))
+	return nil, nil
+}
+
+func (c *compiler) visitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	switch expr.Value {
+	case nil:
+		c.op(expr.Token, OpNil)
+	case true:
+		c.op(expr.Token, OpTrue)
+	case false:
+		c.op(expr.Token, OpFalse)
+	default:
+		j, err := c.constant(expr.Token, expr.Value)
+		if err != nil {
+			return nil, err
+		}
+		c.opShort(expr.Token, OpConstant, j)
+	}
+	return nil, nil
+}
+
+func (c *compiler) visitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	if err := c.expr(expr.Left); err != nil {
+		return nil, err
+	}
+	op := expr.Operator
+	// The left operand stays on the stack as the result when it decides
+	// the outcome.
+	var end int
+	if op.Type == Or {
+		next := c.jump(op, OpJumpIfFalse)
+		end = c.jump(op, OpJump)
+		if err := c.patchJump(op, next); err != nil {
+			return nil, err
+		}
+	} else {
+		end = c.jump(op, OpJumpIfFalse)
+	}
+	c.op(op, OpPop)
+	if err := c.expr(expr.Right); err != nil {
+		return nil, err
+	}
+	return nil, c.patchJump(op, end)
+}
+
+func (c *compiler) visitMapExpr(expr *MapExpr) (Value, error) {
+	if len(expr.Keys) > maxShort {
+		return nil, c.error(expr.LBrace, "too many entries in map literal")
+	}
+	for j := range expr.Keys {
+		if err := c.expr(expr.Keys[j]); err != nil {
+			return nil, err
+		}
+		if err := c.expr(expr.Vals[j]); err != nil {
+			return nil, err
+		}
+	}
+	c.opShort(expr.LBrace, OpMap, len(expr.Keys))
+	return nil, nil
+}
+
+func (c *compiler) visitSetExpr(expr *SetExpr) (Value, error) {
+	if err := c.expr(expr.Object); err != nil {
+		return nil, err
+	}
+	if err := c.expr(expr.Val); err != nil {
+		return nil, err
+	}
+	j, err := c.name(expr.Name)
+	if err != nil {
+		return nil, err
+	}
+	c.opShort(expr.Name, OpSetProperty, j)
+	return nil, nil
+}
+
+func (c *compiler) visitSuperExpr(expr *SuperExpr) (Value, error) {
+	this := expr.Keyword
+	this.Lexeme = "this"
+	if err := c.variable(this, false); err != nil {
+		return nil, err
+	}
+	if err := c.variable(expr.Keyword, false); err != nil {
+		return nil, err
+	}
+	j, err := c.name(expr.Method)
+	if err != nil {
+		return nil, err
+	}
+	c.opShort(expr.Method, OpGetSuper, j)
+	return nil, nil
+}
+
+func (c *compiler) visitThisExpr(expr *ThisExpr) (Value, error) {
+	return nil, c.variable(expr.Keyword, false)
+}
+
+func (c *compiler) visitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	if err := c.expr(expr.Right); err != nil {
+		return nil, err
+	}
+	switch expr.Operator.Type {
+	case Minus:
+		c.op(expr.Operator, OpNegate)
+	case Bang:
+		c.op(expr.Operator, OpNot)
+	default:
+		return nil, c.error(expr.Operator, "unknown unary operator")
+	}
+	return nil, nil
+}
+
+func (c *compiler) visitVarExpr(expr *VarExpr) (Value, error) {
+	return nil, c.variable(expr.Name, false)
+}
+
+func (c *compiler) visitBlockStmt(stmt *BlockStmt) (Value, error) {
+	c.beginScope()
+	for _, s := range stmt.Stmts {
+		if err := c.stmt(s); err != nil {
+			return nil, err
+		}
+	}
+	c.endScope(stmt.RBrace)
+	return nil, nil
+}
+
+func (c *compiler) visitBreakStmt(stmt *BreakStmt) (Value, error) {
+	loop := c.loops[len(c.loops)-1]
+	c.popLocals(stmt.Keyword, loop.depth)
+	loop.breaks = append(loop.breaks, c.jump(stmt.Keyword, OpJump))
+	return nil, nil
+}
+
+func (c *compiler) visitContinueStmt(stmt *ContinueStmt) (Value, error) {
+	loop := c.loops[len(c.loops)-1]
+	c.popLocals(stmt.Keyword, loop.depth)
+	loop.continues = append(loop.continues, c.jump(stmt.Keyword, OpJump))
+	return nil, nil
+}
+
+func (c *compiler) visitClassStmt(stmt *ClassStmt) (Value, error) {
+	name, err := c.name(stmt.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.declare(stmt.Name); err != nil {
+		return nil, err
+	}
+	c.opShort(stmt.Name, OpClass, name)
+	if err := c.define(stmt.Name); err != nil {
+		return nil, err
+	}
+
+	if stmt.Superclass != nil {
+		if err := c.expr(stmt.Superclass); err != nil {
+			return nil, err
+		}
+		// Methods find the superclass in a local called "super" in a
+		// scope around the class body.
+		c.beginScope()
+		super := stmt.Superclass.Name
+		super.Lexeme = "super"
+		if err := c.declare(super); err != nil {
+			return nil, err
+		}
+		if err := c.define(super); err != nil {
+			return nil, err
+		}
+		if err := c.variable(stmt.Name, false); err != nil {
+			return nil, err
+		}
+		c.op(stmt.Superclass.Name, OpInherit)
+	}
+
+	if err := c.variable(stmt.Name, false); err != nil {
+		return nil, err
+	}
+	for _, m := range stmt.Methods {
+		kind := funTypeMethod
+		if m.Name.Lexeme == "init" {
+			kind = funTypeInitializer
+		}
+		if err := c.function(m.Name, kind, m.Name.Lexeme, m.Params, m.Body, m.RBrace); err != nil {
+			return nil, err
+		}
+		j, err := c.name(m.Name)
+		if err != nil {
+			return nil, err
+		}
+		c.opShort(m.Name, OpMethod, j)
+	}
+	c.op(stmt.RBrace, OpPop)
+
+	if stmt.Superclass != nil {
+		c.endScope(stmt.RBrace)
+	}
+	return nil, nil
+}
+
+func (c *compiler) visitExprStmt(stmt *ExprStmt) (Value, error) {
+	if err := c.expr(stmt.Expr); err != nil {
+		return nil, err
+	}
+	c.op(stmt.Semicolon, OpPop)
+	return nil, nil
+}
+
+func (c *compiler) visitFunStmt(stmt *FunStmt) (Value, error) {
+	if err := c.declare(stmt.Name); err != nil {
+		return nil, err
+	}
+	// A local function can refer to itself, so it is defined before its
+	// body is compiled.
+	if c.scopeDepth > 0 {
+		c.locals[len(c.locals)-1].depth = c.scopeDepth
+	}
+	if err := c.function(stmt.Name, funTypeFunction, stmt.Name.Lexeme, stmt.Params, stmt.Body, stmt.RBrace); err != nil {
+		return nil, err
+	}
+	return nil, c.define(stmt.Name)
+}
+
+func (c *compiler) visitIfStmt(stmt *IfStmt) (Value, error) {
+	kw := stmt.Keyword
+	if err := c.expr(stmt.Cond); err != nil {
+		return nil, err
+	}
+	thenJump := c.jump(kw, OpJumpIfFalse)
+	c.op(kw, OpPop)
+	if err := c.stmt(stmt.Then); err != nil {
+		return nil, err
+	}
+	elseJump := c.jump(kw, OpJump)
+	if err := c.patchJump(kw, thenJump); err != nil {
+		return nil, err
+	}
+	c.op(kw, OpPop)
+	if stmt.Else != nil {
+		if err := c.stmt(stmt.Else); err != nil {
+			return nil, err
+		}
+	}
+	return nil, c.patchJump(kw, elseJump)
+}
+
+func (c *compiler) visitPrintStmt(stmt *PrintStmt) (Value, error) {
+	if err := c.expr(stmt.Expr); err != nil {
+		return nil, err
+	}
+	c.op(stmt.Keyword, OpPrint)
+	return nil, nil
+}
+
+func (c *compiler) visitRetStmt(stmt *RetStmt) (Value, error) {
+	if stmt.Val == nil {
+		c.emitReturn(stmt.Keyword)
+		return nil, nil
+	}
+	if err := c.expr(stmt.Val); err != nil {
+		return nil, err
+	}
+	c.op(stmt.Keyword, OpReturn)
+	return nil, nil
+}
+
+func (c *compiler) visitVarStmt(stmt *VarStmt) error {
+	if err := c.declare(stmt.Name); err != nil {
+		return err
+	}
+	if stmt.Init != nil {
+		if err := c.expr(stmt.Init); err != nil {
+			return err
+		}
+	} else {
+		c.op(stmt.Name, OpNil)
+	}
+	return c.define(stmt.Name)
+}
+
+func (c *compiler) visitWhileStmt(stmt *WhileStmt) (Value, error) {
+	kw := stmt.Keyword
+	start := len(c.chunk().Code)
+	if err := c.expr(stmt.Cond); err != nil {
+		return nil, err
+	}
+	exit := c.jump(kw, OpJumpIfFalse)
+	c.op(kw, OpPop)
+
+	loop := &loopInfo{depth: c.scopeDepth}
+	c.loops = append(c.loops, loop)
+	err := c.stmt(stmt.Body)
+	c.loops = c.loops[:len(c.loops)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	for _, at := range loop.continues {
+		if err := c.patchJump(kw, at); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.Incr != nil {
+		if err := c.expr(stmt.Incr); err != nil {
+			return nil, err
+		}
+		c.op(kw, OpPop)
+	}
+	if err := c.loop(kw, start); err != nil {
+		return nil, err
+	}
+
+	if err := c.patchJump(kw, exit); err != nil {
+		return nil, err
+	}
+	c.op(kw, OpPop)
+	// break leaves after the condition has been popped.
+	for _, at := range loop.breaks {
+		if err := c.patchJump(kw, at); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
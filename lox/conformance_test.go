@@ -0,0 +1,299 @@
+package lox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"glox/lox"
+)
+
+// The scripts in sampleDir state what they should do in comments, following
+// the Crafting Interpreters test suite:
+//
+//	print 1 + 2; // expect: 3
+//	nil();       // expect runtime error: can only call functions and classes, got: nil
+//	return;      // expect compile error: resolve error at 'return': can not return from top-level code
+//
+// Output expectations are matched in order against the lines the script
+// prints. Error expectations must be raised on the line of the comment; a
+// script can expect at most one runtime error but any number of compile
+// errors. A script with no error expectations must run without error.
+//
+// Every script is run on each backend, and once more saved as a compiled
+// program and loaded back.
+const sampleDir = "../resources/sample-code"
+
+var (
+	expectRe = regexp.MustCompile(`// expect( runtime error| compile error)?: (.*)$`)
+
+	// errLineRe splits "[file:line:col] message" lines of error output.
+	errLineRe = regexp.MustCompile(`^\[.*:(\d+):\d+\] (.*)$`)
+)
+
+// diagnostic is an error message and the line it was reported on.
+type diagnostic struct {
+	line int
+	msg  string
+}
+
+func (d diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s", d.line, d.msg)
+}
+
+type expectations struct {
+	output  []string
+	runtime *diagnostic
+	compile []diagnostic
+}
+
+func TestConformance(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(sampleDir, "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no scripts in %s", sampleDir)
+	}
+	for _, mode := range []struct {
+		name string
+		run  runFunc
+	}{
+		{"tree", runOn(lox.TreeWalker)},
+		{"vm", runOn(lox.BytecodeVM)},
+		{"compiled", runCompiled},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			for _, path := range paths {
+				t.Run(filepath.Base(path), func(t *testing.T) {
+					runScript(t, mode.run, path)
+				})
+			}
+		})
+	}
+}
+
+// runFunc runs the script src from path on a new interpreter made with opts.
+type runFunc func(ctx context.Context, opts []lox.Option, path, src string) error
+
+func runOn(backend lox.Backend) runFunc {
+	return func(ctx context.Context, opts []lox.Option, path, src string) error {
+		_, err := lox.New(append(opts, lox.WithBackend(backend))...).RunFile(ctx, path, src)
+		return err
+	}
+}
+
+// runCompiled compiles src, saves and loads the program, and runs it.
+func runCompiled(ctx context.Context, opts []lox.Option, path, src string) error {
+	interp := lox.New(opts...)
+	prog, err := interp.Compile(path, src)
+	if err != nil {
+		return err
+	}
+	var saved bytes.Buffer
+	if _, err := prog.WriteTo(&saved); err != nil {
+		return err
+	}
+	if prog, err = lox.ReadProgram(&saved); err != nil {
+		return err
+	}
+	_, err = interp.RunProgram(ctx, prog)
+	return err
+}
+
+func runScript(t *testing.T, run runFunc, path string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := parseExpectations(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := []lox.Option{
+		lox.WithStdout(&stdout),
+		lox.WithStderr(&stderr),
+		lox.WithStdin(strings.NewReader("")),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	runErr := run(ctx, opts, path, string(src))
+
+	checkOutput(t, want.output, stdout.String())
+
+	var cerr *lox.CompileError
+	var rerr *lox.RuntimeError
+	switch {
+	case len(want.compile) > 0:
+		if !errors.As(runErr, &cerr) {
+			t.Fatalf("want compile errors %v, got: %v", want.compile, runErr)
+		}
+		got, err := parseDiagnostics(cerr.Error())
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDiagnostics(t, want.compile, got)
+	case want.runtime != nil:
+		if !errors.As(runErr, &rerr) {
+			t.Fatalf("want runtime error %v, got: %v", *want.runtime, runErr)
+		}
+		got := diagnostic{line: rerr.Token.Line, msg: rerr.Msg}
+		if got != *want.runtime {
+			t.Errorf("runtime error:\n got: %v\nwant: %v", got, *want.runtime)
+		}
+	case runErr != nil:
+		t.Fatalf("unexpected error: %v", runErr)
+	}
+}
+
+// TestFormat checks that formatting a sample script is idempotent and
+// does not change what it prints.
+func TestFormat(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(sampleDir, "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formatted, err := lox.Format(path, string(src))
+			var cerr *lox.CompileError
+			if errors.As(err, &cerr) {
+				t.Skip("script does not parse")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := lox.Format(path, formatted)
+			if err != nil {
+				t.Fatalf("formatted script: %v\n%s", err, formatted)
+			}
+			if again != formatted {
+				t.Errorf("formatting is not idempotent:\n got: %q\nwant: %q", again, formatted)
+			}
+			if want, got := runOutput(path, string(src)), runOutput(path, formatted); got != want {
+				t.Errorf("formatted script prints\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+// runOutput runs src and returns what it printed and the error, if any.
+func runOutput(path, src string) string {
+	var out bytes.Buffer
+	interp := lox.New(lox.WithStdout(&out), lox.WithStdin(strings.NewReader("")))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := interp.RunFile(ctx, path, src); err != nil {
+		var rerr *lox.RuntimeError
+		if errors.As(err, &rerr) {
+			fmt.Fprintf(&out, "runtime error: %s\n", rerr.Msg)
+		} else {
+			fmt.Fprintf(&out, "%v\n", err)
+		}
+	}
+	return out.String()
+}
+
+func parseExpectations(src string) (expectations, error) {
+	var want expectations
+	for j, line := range strings.Split(src, "\n") {
+		m := expectRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		d := diagnostic{line: j + 1, msg: m[2]}
+		switch m[1] {
+		case "":
+			want.output = append(want.output, m[2])
+		case " runtime error":
+			if want.runtime != nil {
+				return want, fmt.Errorf("line %d: only one runtime error can be expected", d.line)
+			}
+			want.runtime = &d
+		case " compile error":
+			want.compile = append(want.compile, d)
+		}
+	}
+	if want.runtime != nil && len(want.compile) > 0 {
+		return want, fmt.Errorf("a script can not expect both compile and runtime errors")
+	}
+	return want, nil
+}
+
+// parseDiagnostics splits the text of a CompileError into one diagnostic
+// per reported error.
+func parseDiagnostics(text string) ([]diagnostic, error) {
+	var ds []diagnostic
+	for _, line := range strings.Split(text, "\n") {
+		m := errLineRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("malformed error line %q", line)
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, diagnostic{line: n, msg: m[2]})
+	}
+	return ds, nil
+}
+
+func checkOutput(t *testing.T, want []string, out string) {
+	t.Helper()
+	var got []string
+	if out != "" {
+		got = strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	}
+	for j := 0; j < len(want) || j < len(got); j++ {
+		switch {
+		case j >= len(got):
+			t.Errorf("output line %d: missing, want %q", j+1, want[j])
+		case j >= len(want):
+			t.Errorf("output line %d: unexpected %q", j+1, got[j])
+		case got[j] != want[j]:
+			t.Errorf("output line %d:\n got: %q\nwant: %q", j+1, got[j], want[j])
+		}
+	}
+}
+
+// checkDiagnostics compares compile errors regardless of the order they
+// were reported in.
+func checkDiagnostics(t *testing.T, want, got []diagnostic) {
+	t.Helper()
+	sortDiagnostics(want)
+	sortDiagnostics(got)
+	for j := 0; j < len(want) || j < len(got); j++ {
+		switch {
+		case j >= len(got):
+			t.Errorf("missing compile error %v", want[j])
+		case j >= len(want):
+			t.Errorf("unexpected compile error %v", got[j])
+		case got[j] != want[j]:
+			t.Errorf("compile error:\n got: %v\nwant: %v", got[j], want[j])
+		}
+	}
+}
+
+func sortDiagnostics(ds []diagnostic) {
+	sort.Slice(ds, func(j, k int) bool {
+		if ds[j].line != ds[k].line {
+			return ds[j].line < ds[k].line
+		}
+		return ds[j].msg < ds[k].msg
+	})
+}
@@ -0,0 +1,91 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Disassemble writes a listing of the bytecode of p to w, one function
+// after another, starting with the script. Every instruction is shown
+// with its offset, source position, opcode and operands:
+//
+//	0003     1:5 OP_DEFINE_GLOBAL      1 "a"
+//
+// If source is the text p was compiled from, each source line is printed
+// above the instructions compiled from it.
+func (p *Program) Disassemble(w io.Writer, source string) error {
+	var lines []string
+	if source != "" {
+		lines = strings.Split(source, "\n")
+	}
+	var b strings.Builder
+	disassemble(&b, p.script, "<script>", lines)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func disassemble(b *strings.Builder, p *funProto, title string, source []string) {
+	fmt.Fprintf(b, "== %s ==\n", title)
+	c := &p.chunk
+	prevLine := 0
+	for off := 0; off < len(c.Code); {
+		line, col := c.position(off)
+		if line != prevLine && line > 0 && line <= len(source) {
+			fmt.Fprintf(b, "%14d | %s\n", line, strings.TrimRight(source[line-1], "\r"))
+		}
+		prevLine = line
+		pos := "-"
+		if line > 0 {
+			pos = fmt.Sprintf("%d:%d", line, col)
+		}
+		off = disassembleInstr(b, c, off, pos)
+	}
+	for _, k := range c.Constants {
+		if fn, ok := k.(*funProto); ok {
+			b.WriteString("\n")
+			disassemble(b, fn, fn.String(), source)
+		}
+	}
+}
+
+// disassembleInstr writes the instruction at offset and returns the offset
+// of the next one.
+func disassembleInstr(b *strings.Builder, c *Chunk, off int, pos string) int {
+	op := Opcode(c.Code[off])
+	fmt.Fprintf(b, "%04d %7s %v", off, pos, op)
+	if int(op) >= len(opcodes) || opcodes[op].operand == noOperand {
+		b.WriteString("\n")
+		return off + 1
+	}
+	fmt.Fprintf(b, "%*s", 18-len(op.String()), "")
+	next := off + 1 + opcodes[op].operand.width()
+	switch opcodes[op].operand {
+	case constOperand, nameOperand:
+		j := c.readShort(off + 1)
+		fmt.Fprintf(b, " %4d %s", j, repr(c.Constants[j]))
+	case slotOperand, upvalueOperand, argcOperand:
+		fmt.Fprintf(b, " %4d", c.Code[off+1])
+	case countOperand:
+		fmt.Fprintf(b, " %4d", c.readShort(off+1))
+	case jumpOperand:
+		fmt.Fprintf(b, " %4d -> %04d", c.readShort(off+1), next+c.readShort(off+1))
+	case loopOperand:
+		fmt.Fprintf(b, " %4d -> %04d", c.readShort(off+1), next-c.readShort(off+1))
+	case closureOperand:
+		j := c.readShort(off + 1)
+		fn := c.Constants[j].(*funProto)
+		fmt.Fprintf(b, " %4d %s\n", j, fn)
+		for k := 0; k < fn.upvalues; k++ {
+			kind := "upvalue"
+			if c.Code[next] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(b, "%04d %7s %-18s %4s %s %d\n", next, "", "|", "", kind, c.Code[next+1])
+			next += 2
+		}
+		return next
+	}
+	b.WriteString("\n")
+	return next
+}
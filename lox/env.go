@@ -1,20 +1,27 @@
-package main
+package lox
 
 import (
 	"fmt"
 )
 
 type Env struct {
-	vals      map[string]any
+	vals      map[string]Value
 	enclosing *Env
 }
 
 func NewEnv() *Env {
-	vals := make(map[string]any, 0)
+	vals := make(map[string]Value, 0)
 	return &Env{vals: vals}
 }
 
-func (e *Env) Get(name string) (any, error) {
+// NewEnclosedEnv returns an empty Env nested inside enclosing.
+func NewEnclosedEnv(enclosing *Env) *Env {
+	e := NewEnv()
+	e.enclosing = enclosing
+	return e
+}
+
+func (e *Env) Get(name string) (Value, error) {
 	if v, ok := e.vals[name]; ok {
 		return v, nil
 	}
@@ -25,7 +32,7 @@ func (e *Env) Get(name string) (any, error) {
 	return nil, fmt.Errorf("undefined for name: %s", name)
 }
 
-func (e *Env) Assign(name string, val any) error {
+func (e *Env) Assign(name string, val Value) error {
 	if _, ok := e.vals[name]; ok {
 		e.vals[name] = val
 		return nil
@@ -38,7 +45,7 @@ func (e *Env) Assign(name string, val any) error {
 	return fmt.Errorf("unefineable variable: %s", name)
 }
 
-func (e *Env) Define(key string, val any) {
+func (e *Env) Define(key string, val Value) {
 	e.vals[key] = val
 }
 
@@ -50,7 +57,7 @@ func (e *Env) Ancestor(dist int) *Env {
 	return environ
 }
 
-func (e *Env) GetAt(dist int, name string) (any, error) {
+func (e *Env) GetAt(dist int, name string) (Value, error) {
 	v, ok := e.Ancestor(dist).vals[name]
 	if !ok {
 		return nil, fmt.Errorf("can't get, at: %s, %d", name, dist)
@@ -58,23 +65,6 @@ func (e *Env) GetAt(dist int, name string) (any, error) {
 	return v, nil
 }
 
-func (e *Env) AssignAt(dist int, name string, val any) {
+func (e *Env) AssignAt(dist int, name string, val Value) {
 	e.Ancestor(dist).vals[name] = val
 }
-
-func CopyFrom(src *Env) *Env {
-	if src == nil {
-		return &Env{}
-	}
-	dst := NewEnv()
-	if src.enclosing != nil {
-		dst.enclosing = CopyFrom(src.enclosing)
-	}
-	if len(src.vals) > 0 {
-		dst.vals = make(map[string]any)
-		for k, v := range src.vals {
-			dst.vals[k] = v
-		}
-	}
-	return dst
-}
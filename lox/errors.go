@@ -0,0 +1,69 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuntimeError is raised while a script executes. Token is where in the
+// source it went wrong and Trace the script-level call stack at that
+// point, innermost call first.
+type RuntimeError struct {
+	Token Token
+	Msg   string
+	Trace []Frame
+
+	// Err is the underlying cause, if any: an error from host code, a
+	// context error or one of the limit errors such as ErrStepLimit.
+	Err error
+}
+
+// Frame is one entry of a RuntimeError's stack trace.
+type Frame struct {
+	// Function is the name of the function, or "script" for top-level code.
+	Function string
+	Line     int
+}
+
+// maxTraceLines is how many frames of a long trace Error shows, split
+// between the innermost and outermost calls.
+const maxTraceLines = 20
+
+func (e *RuntimeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] runtime error: %s", e.Token.Pos(), e.Msg)
+	half := maxTraceLines / 2
+	for j, f := range e.Trace {
+		if len(e.Trace) > maxTraceLines && j >= half && j < len(e.Trace)-half {
+			if j == half {
+				fmt.Fprintf(&b, "\n    ... %d more calls", len(e.Trace)-maxTraceLines)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "\n    [line %d] in %s", f.Line, f.Function)
+	}
+	return b.String()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// callFrame is a function call the interpreter is currently inside.
+type callFrame struct {
+	name string
+	site Token
+}
+
+// runtimeError creates a RuntimeError at tok, with a trace built from the
+// calls currently in progress.
+func (i *Interpreter) runtimeError(tok Token, format string, args ...any) *RuntimeError {
+	trace := make([]Frame, 0, len(i.frames)+1)
+	line := tok.Line
+	for j := len(i.frames) - 1; j >= 0; j-- {
+		trace = append(trace, Frame{Function: i.frames[j].name + "()", Line: line})
+		line = i.frames[j].site.Line
+	}
+	trace = append(trace, Frame{Function: "script", Line: line})
+	return &RuntimeError{Token: tok, Msg: fmt.Sprintf(format, args...), Trace: trace}
+}
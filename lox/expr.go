@@ -0,0 +1,284 @@
+package lox
+
+type Expr interface {
+	Accept(v ExprVisitor) (Value, error)
+
+	// Span is the source range the expression was parsed from.
+	Span() Span
+}
+
+// ExprVisitor is a pass over expressions. The interpreter returns the
+// Value of the expression; other passes, such as the printers, return
+// their own results.
+type ExprVisitor interface {
+	visitAssignExpr(expr *AssignExpr) (Value, error)
+	visitBinaryExpr(expr *BinaryExpr) (Value, error)
+	visitCallExpr(expr *CallExpr) (Value, error)
+	visitFunctionExpr(expr *FunctionExpr) (Value, error)
+	visitGetExpr(expr *GetExpr) (Value, error)
+	visitGroupingExpr(expr *GroupingExpr) (Value, error)
+	visitIndexGetExpr(expr *IndexGetExpr) (Value, error)
+	visitIndexSetExpr(expr *IndexSetExpr) (Value, error)
+	visitListExpr(expr *ListExpr) (Value, error)
+	visitLiteralExpr(expr *LiteralExpr) (Value, error)
+	visitLogicalExpr(expr *LogicalExpr) (Value, error)
+	visitMapExpr(expr *MapExpr) (Value, error)
+	visitSetExpr(expr *SetExpr) (Value, error)
+	visitSuperExpr(expr *SuperExpr) (Value, error)
+	visitThisExpr(expr *ThisExpr) (Value, error)
+	visitUnaryExpr(expr *UnaryExpr) (Value, error)
+	visitVarExpr(expr *VarExpr) (Value, error)
+}
+
+// resolution is where the Resolver found the variable an expression refers
+// to: depth scopes out from the expression, if local. It is kept on the
+// expression so it goes away with the syntax tree.
+type resolution struct {
+	local bool
+	depth int
+}
+
+type AssignExpr struct {
+	Name  Token
+	Value Expr
+
+	res resolution
+}
+
+func (a *AssignExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitAssignExpr(a)
+}
+
+func (a *AssignExpr) Span() Span {
+	return spanOf(a.Name.Span(), a.Value.Span())
+}
+
+type BinaryExpr struct {
+	Left     Expr
+	Right    Expr
+	Operator Token
+}
+
+func (a *BinaryExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitBinaryExpr(a)
+}
+
+func (a *BinaryExpr) Span() Span {
+	return spanOf(a.Left.Span(), a.Right.Span())
+}
+
+type CallExpr struct {
+	Callee Expr
+	Paren  Token
+	Args   []Expr
+}
+
+func (a *CallExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitCallExpr(a)
+}
+
+func (a *CallExpr) Span() Span {
+	return spanOf(a.Callee.Span(), a.Paren.Span())
+}
+
+// FunctionExpr is an anonymous function, fun (a, b) { ... }.
+type FunctionExpr struct {
+	Keyword Token
+	Params  []Token
+	Body    []Stmt
+	RBrace  Token
+}
+
+func (a *FunctionExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitFunctionExpr(a)
+}
+
+func (a *FunctionExpr) Span() Span {
+	return spanOf(a.Keyword.Span(), a.RBrace.Span())
+}
+
+type GetExpr struct {
+	Object Expr
+	Name   Token
+}
+
+func (a *GetExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitGetExpr(a)
+}
+
+func (a *GetExpr) Span() Span {
+	return spanOf(a.Object.Span(), a.Name.Span())
+}
+
+type GroupingExpr struct {
+	LParen Token
+	Expr   Expr
+	RParen Token
+}
+
+func (a *GroupingExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitGroupingExpr(a)
+}
+
+func (a *GroupingExpr) Span() Span {
+	return spanOf(a.LParen.Span(), a.RParen.Span())
+}
+
+// IndexGetExpr reads an element: Object[Index].
+type IndexGetExpr struct {
+	Object   Expr
+	Bracket  Token
+	Index    Expr
+	RBracket Token
+}
+
+func (a *IndexGetExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitIndexGetExpr(a)
+}
+
+func (a *IndexGetExpr) Span() Span {
+	return spanOf(a.Object.Span(), a.RBracket.Span())
+}
+
+// IndexSetExpr assigns to an element: Object[Index] = Val.
+type IndexSetExpr struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+	Val     Expr
+}
+
+func (a *IndexSetExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitIndexSetExpr(a)
+}
+
+func (a *IndexSetExpr) Span() Span {
+	return spanOf(a.Object.Span(), a.Val.Span())
+}
+
+type ListExpr struct {
+	LBracket Token
+	Elems    []Expr
+	RBracket Token
+}
+
+func (a *ListExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitListExpr(a)
+}
+
+func (a *ListExpr) Span() Span {
+	return spanOf(a.LBracket.Span(), a.RBracket.Span())
+}
+
+type LiteralExpr struct {
+	Token Token
+	Value Value
+}
+
+func (a *LiteralExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitLiteralExpr(a)
+}
+
+func (a *LiteralExpr) Span() Span {
+	return a.Token.Span()
+}
+
+type LogicalExpr struct {
+	Left     Expr
+	Operator Token
+	Right    Expr
+}
+
+func (a *LogicalExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitLogicalExpr(a)
+}
+
+func (a *LogicalExpr) Span() Span {
+	return spanOf(a.Left.Span(), a.Right.Span())
+}
+
+// MapExpr is a map literal. Keys[j] maps to Vals[j].
+type MapExpr struct {
+	LBrace Token
+	Keys   []Expr
+	Vals   []Expr
+	RBrace Token
+}
+
+func (a *MapExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitMapExpr(a)
+}
+
+func (a *MapExpr) Span() Span {
+	return spanOf(a.LBrace.Span(), a.RBrace.Span())
+}
+
+type SetExpr struct {
+	Object Expr
+	Name   Token
+	Val    Expr
+}
+
+func (a *SetExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitSetExpr(a)
+}
+
+func (a *SetExpr) Span() Span {
+	return spanOf(a.Object.Span(), a.Val.Span())
+}
+
+type SuperExpr struct {
+	Keyword Token
+	Method  Token
+
+	res resolution
+}
+
+func (a *SuperExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitSuperExpr(a)
+}
+
+func (a *SuperExpr) Span() Span {
+	return spanOf(a.Keyword.Span(), a.Method.Span())
+}
+
+type ThisExpr struct {
+	Keyword Token
+
+	res resolution
+}
+
+func (a *ThisExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitThisExpr(a)
+}
+
+func (a *ThisExpr) Span() Span {
+	return a.Keyword.Span()
+}
+
+type UnaryExpr struct {
+	Operator Token
+	Right    Expr
+}
+
+func (a *UnaryExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitUnaryExpr(a)
+}
+
+func (a *UnaryExpr) Span() Span {
+	return spanOf(a.Operator.Span(), a.Right.Span())
+}
+
+type VarExpr struct {
+	Name Token
+
+	res resolution
+}
+
+func (a *VarExpr) Accept(v ExprVisitor) (Value, error) {
+	return v.visitVarExpr(a)
+}
+
+func (a *VarExpr) Span() Span {
+	return a.Name.Span()
+}
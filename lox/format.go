@@ -0,0 +1,411 @@
+package lox
+
+import (
+	"sort"
+	"strings"
+)
+
+// Format returns source reformatted in the canonical style: one statement
+// per line, blocks indented by two spaces with the opening brace on the
+// line of their statement, single spaces around binary operators and
+// after commas, and at most one blank line between statements.
+//
+// Comments are kept. Those on a line of their own stay before the
+// statement or closing brace that follows them; those inside a statement
+// are moved to the end of its last line.
+func Format(filename, source string) (string, error) {
+	lex := Lexer{Source: source, Tokens: []Token{}, File: filename}
+	if err := lex.Scan(); err != nil {
+		return "", &CompileError{err}
+	}
+	stmts, err := NewParser(lex.Tokens).Parse()
+	if err != nil {
+		return "", &CompileError{err}
+	}
+	f := &formatter{tokens: lex.Tokens, comments: lex.Comments}
+	for _, s := range stmts {
+		f.stmt(s)
+	}
+	f.flushComments(len(source))
+	return f.buf.String(), nil
+}
+
+type formatter struct {
+	buf    strings.Builder
+	indent int
+
+	tokens   []Token
+	comments []Token
+
+	// prevLine is the last source line of what was written last in the
+	// current block, zero at the start of a block.
+	prevLine int
+}
+
+// stmt writes s on lines of its own, after the comments before it.
+func (f *formatter) stmt(s Stmt) {
+	start := stmtStart(s)
+	f.flushComments(start.Offset)
+	f.line(start.Line)
+	s.Accept(f)
+
+	end := s.Span().End
+	var trailing []string
+	for len(f.comments) > 0 && f.isTrailing(f.comments[0], end) {
+		trailing = append(trailing, f.comments[0].Lexeme)
+		f.comments = f.comments[1:]
+	}
+	if len(trailing) > 0 {
+		f.buf.WriteString(" " + strings.Join(trailing, " "))
+	}
+	f.buf.WriteString("\n")
+	f.prevLine = end.Line
+}
+
+// isTrailing reports whether c belongs at the end of a statement ending
+// at end: it is inside the statement, or follows it on its last line.
+func (f *formatter) isTrailing(c Token, end Pos) bool {
+	if c.Offset < end.Offset {
+		return true
+	}
+	if c.Line != end.Line {
+		return false
+	}
+	j := sort.Search(len(f.tokens), func(j int) bool { return f.tokens[j].Offset >= end.Offset })
+	return j == len(f.tokens) || f.tokens[j].Offset > c.Offset
+}
+
+// flushComments writes the comments before offset on lines of their own.
+func (f *formatter) flushComments(offset int) {
+	for len(f.comments) > 0 && f.comments[0].Offset < offset {
+		c := f.comments[0]
+		f.comments = f.comments[1:]
+		f.line(c.Line)
+		f.buf.WriteString(c.Lexeme + "\n")
+		f.prevLine = c.Line
+	}
+}
+
+// line starts a line for something from source line n, keeping one blank
+// line if the source had any since the previous one.
+func (f *formatter) line(n int) {
+	if f.prevLine > 0 && n > f.prevLine+1 {
+		f.buf.WriteString("\n")
+	}
+	f.buf.WriteString(strings.Repeat("  ", f.indent))
+}
+
+// block writes stmts in braces, the closing one at rbrace.
+func (f *formatter) block(stmts []Stmt, rbrace Token) {
+	if len(stmts) == 0 && (len(f.comments) == 0 || f.comments[0].Offset > rbrace.Offset) {
+		f.buf.WriteString("{}")
+		return
+	}
+	f.buf.WriteString("{\n")
+	prevLine := f.prevLine
+	f.indent++
+	f.prevLine = 0
+	for _, s := range stmts {
+		f.stmt(s)
+	}
+	f.flushComments(rbrace.Offset)
+	f.indent--
+	f.buf.WriteString(strings.Repeat("  ", f.indent) + "}")
+	f.prevLine = prevLine
+}
+
+// stmtStart returns where s starts in the source. A desugared for loop
+// starts at its keyword, before the initializer.
+func stmtStart(s Stmt) Pos {
+	if b, ok := s.(*BlockStmt); ok {
+		if loop := forLoop(b); loop != nil {
+			return loop.Keyword.Pos()
+		}
+	}
+	return s.Span().Start
+}
+
+// forLoop returns the loop of b if b is a for loop with an initializer,
+// which the parser desugars to a block without braces.
+func forLoop(b *BlockStmt) *WhileStmt {
+	if b.LBrace.Span().IsValid() || len(b.Stmts) != 2 {
+		return nil
+	}
+	loop, ok := b.Stmts[1].(*WhileStmt)
+	if !ok || loop.Keyword.Type != For {
+		return nil
+	}
+	return loop
+}
+
+func (f *formatter) expr(e Expr) {
+	e.Accept(f)
+}
+
+func (f *formatter) exprs(es []Expr) {
+	for j, e := range es {
+		if j > 0 {
+			f.buf.WriteString(", ")
+		}
+		f.expr(e)
+	}
+}
+
+func (f *formatter) function(params []Token, body []Stmt, rbrace Token) {
+	f.buf.WriteString("(")
+	for j, p := range params {
+		if j > 0 {
+			f.buf.WriteString(", ")
+		}
+		f.buf.WriteString(p.Lexeme)
+	}
+	f.buf.WriteString(") ")
+	f.block(body, rbrace)
+}
+
+func (f *formatter) visitAssignExpr(expr *AssignExpr) (Value, error) {
+	f.buf.WriteString(expr.Name.Lexeme + " = ")
+	f.expr(expr.Value)
+	return nil, nil
+}
+
+func (f *formatter) visitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	f.expr(expr.Left)
+	f.buf.WriteString(" " + expr.Operator.Lexeme + " ")
+	f.expr(expr.Right)
+	return nil, nil
+}
+
+func (f *formatter) visitCallExpr(expr *CallExpr) (Value, error) {
+	f.expr(expr.Callee)
+	f.buf.WriteString("(")
+	f.exprs(expr.Args)
+	f.buf.WriteString(")")
+	return nil, nil
+}
+
+func (f *formatter) visitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	f.buf.WriteString("fun ")
+	f.function(expr.Params, expr.Body, expr.RBrace)
+	return nil, nil
+}
+
+func (f *formatter) visitGetExpr(expr *GetExpr) (Value, error) {
+	f.expr(expr.Object)
+	f.buf.WriteString("." + expr.Name.Lexeme)
+	return nil, nil
+}
+
+func (f *formatter) visitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	f.buf.WriteString("(")
+	f.expr(expr.Expr)
+	f.buf.WriteString(")")
+	return nil, nil
+}
+
+func (f *formatter) visitIndexGetExpr(expr *IndexGetExpr) (Value, error) {
+	f.expr(expr.Object)
+	f.buf.WriteString("[")
+	f.expr(expr.Index)
+	f.buf.WriteString("]")
+	return nil, nil
+}
+
+func (f *formatter) visitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	f.expr(expr.Object)
+	f.buf.WriteString("[")
+	f.expr(expr.Index)
+	f.buf.WriteString("] = ")
+	f.expr(expr.Val)
+	return nil, nil
+}
+
+func (f *formatter) visitListExpr(expr *ListExpr) (Value, error) {
+	f.buf.WriteString("[")
+	f.exprs(expr.Elems)
+	f.buf.WriteString("]")
+	return nil, nil
+}
+
+func (f *formatter) visitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	if expr.Token.Span().IsValid() {
+		f.buf.WriteString(expr.Token.Lexeme)
+	} else {
+		f.buf.WriteString(repr(expr.Value))
+	}
+	return nil, nil
+}
+
+func (f *formatter) visitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	f.expr(expr.Left)
+	f.buf.WriteString(" " + expr.Operator.Lexeme + " ")
+	f.expr(expr.Right)
+	return nil, nil
+}
+
+func (f *formatter) visitMapExpr(expr *MapExpr) (Value, error) {
+	f.buf.WriteString("{")
+	for j := range expr.Keys {
+		if j > 0 {
+			f.buf.WriteString(", ")
+		}
+		f.expr(expr.Keys[j])
+		f.buf.WriteString(": ")
+		f.expr(expr.Vals[j])
+	}
+	f.buf.WriteString("}")
+	return nil, nil
+}
+
+func (f *formatter) visitSetExpr(expr *SetExpr) (Value, error) {
+	f.expr(expr.Object)
+	f.buf.WriteString("." + expr.Name.Lexeme + " = ")
+	f.expr(expr.Val)
+	return nil, nil
+}
+
+func (f *formatter) visitSuperExpr(expr *SuperExpr) (Value, error) {
+	f.buf.WriteString("super." + expr.Method.Lexeme)
+	return nil, nil
+}
+
+func (f *formatter) visitThisExpr(expr *ThisExpr) (Value, error) {
+	f.buf.WriteString("this")
+	return nil, nil
+}
+
+func (f *formatter) visitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	f.buf.WriteString(expr.Operator.Lexeme)
+	f.expr(expr.Right)
+	return nil, nil
+}
+
+func (f *formatter) visitVarExpr(expr *VarExpr) (Value, error) {
+	f.buf.WriteString(expr.Name.Lexeme)
+	return nil, nil
+}
+
+func (f *formatter) visitBlockStmt(stmt *BlockStmt) (Value, error) {
+	if loop := forLoop(stmt); loop != nil {
+		f.forLoop(stmt.Stmts[0], loop)
+		return nil, nil
+	}
+	f.block(stmt.Stmts, stmt.RBrace)
+	return nil, nil
+}
+
+func (f *formatter) visitBreakStmt(stmt *BreakStmt) (Value, error) {
+	f.buf.WriteString("break;")
+	return nil, nil
+}
+
+func (f *formatter) visitClassStmt(stmt *ClassStmt) (Value, error) {
+	f.buf.WriteString("class " + stmt.Name.Lexeme + " ")
+	if stmt.Superclass != nil {
+		f.buf.WriteString("< " + stmt.Superclass.Name.Lexeme + " ")
+	}
+	methods := make([]Stmt, len(stmt.Methods))
+	for j, m := range stmt.Methods {
+		methods[j] = m
+	}
+	f.block(methods, stmt.RBrace)
+	return nil, nil
+}
+
+func (f *formatter) visitContinueStmt(stmt *ContinueStmt) (Value, error) {
+	f.buf.WriteString("continue;")
+	return nil, nil
+}
+
+func (f *formatter) visitExprStmt(stmt *ExprStmt) (Value, error) {
+	f.expr(stmt.Expr)
+	f.buf.WriteString(";")
+	return nil, nil
+}
+
+func (f *formatter) visitFunStmt(stmt *FunStmt) (Value, error) {
+	if stmt.Keyword.Span().IsValid() {
+		f.buf.WriteString("fun ")
+	}
+	f.buf.WriteString(stmt.Name.Lexeme)
+	f.function(stmt.Params, stmt.Body, stmt.RBrace)
+	return nil, nil
+}
+
+func (f *formatter) visitIfStmt(stmt *IfStmt) (Value, error) {
+	f.buf.WriteString("if (")
+	f.expr(stmt.Cond)
+	f.buf.WriteString(") ")
+	stmt.Then.Accept(f)
+	if stmt.Else == nil {
+		return nil, nil
+	}
+	if b, ok := stmt.Then.(*BlockStmt); ok && forLoop(b) == nil {
+		f.buf.WriteString(" else ")
+	} else {
+		f.buf.WriteString("\n" + strings.Repeat("  ", f.indent) + "else ")
+	}
+	stmt.Else.Accept(f)
+	return nil, nil
+}
+
+func (f *formatter) visitPrintStmt(stmt *PrintStmt) (Value, error) {
+	f.buf.WriteString("print ")
+	f.expr(stmt.Expr)
+	f.buf.WriteString(";")
+	return nil, nil
+}
+
+func (f *formatter) visitRetStmt(stmt *RetStmt) (Value, error) {
+	f.buf.WriteString("return")
+	if stmt.Val != nil {
+		f.buf.WriteString(" ")
+		f.expr(stmt.Val)
+	}
+	f.buf.WriteString(";")
+	return nil, nil
+}
+
+func (f *formatter) visitVarStmt(stmt *VarStmt) error {
+	f.buf.WriteString("var " + stmt.Name.Lexeme)
+	if stmt.Init != nil {
+		f.buf.WriteString(" = ")
+		f.expr(stmt.Init)
+	}
+	f.buf.WriteString(";")
+	return nil
+}
+
+func (f *formatter) visitWhileStmt(stmt *WhileStmt) (Value, error) {
+	if stmt.Keyword.Type == For {
+		f.forLoop(nil, stmt)
+		return nil, nil
+	}
+	f.buf.WriteString("while (")
+	f.expr(stmt.Cond)
+	f.buf.WriteString(") ")
+	stmt.Body.Accept(f)
+	return nil, nil
+}
+
+// forLoop writes the for loop that was desugared to init, if any, and loop.
+func (f *formatter) forLoop(init Stmt, loop *WhileStmt) {
+	f.buf.WriteString("for (")
+	if init != nil {
+		init.Accept(f)
+	} else {
+		f.buf.WriteString(";")
+	}
+	// The parser makes up a true condition when there is none.
+	if lit, ok := loop.Cond.(*LiteralExpr); !ok || lit.Token.Span().IsValid() {
+		f.buf.WriteString(" ")
+		f.expr(loop.Cond)
+	}
+	f.buf.WriteString(";")
+	if loop.Incr != nil {
+		f.buf.WriteString(" ")
+		f.expr(loop.Incr)
+	}
+	f.buf.WriteString(") ")
+	loop.Body.Accept(f)
+}
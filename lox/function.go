@@ -0,0 +1,61 @@
+package lox
+
+// Function is a Lox function, method or anonymous function expression
+// together with the environment it closes over.
+type Function struct {
+	// name is empty for anonymous functions.
+	name          string
+	params        []Token
+	body          []Stmt
+	closure       *Env
+	isInitializer bool
+}
+
+// newFunStmt makes a function from a named declaration.
+func newFunStmt(stmt *FunStmt, closure *Env) *Function {
+	return &Function{
+		name:    stmt.Name.Lexeme,
+		params:  stmt.Params,
+		body:    stmt.Body,
+		closure: closure,
+	}
+}
+
+func (f *Function) Call(interp *Interpreter, args []Value) (Value, error) {
+	env := NewEnclosedEnv(f.closure)
+	for j := 0; j < len(f.params); j++ {
+		env.Define(f.params[j].Lexeme, args[j])
+	}
+	var ret Value
+	if err := interp.executeBlock(f.body, env); err != nil {
+		fr, ok := err.(*FunRet)
+		if !ok {
+			return nil, err
+		}
+		ret = fr.Val
+	}
+	if f.isInitializer {
+		return f.closure.GetAt(0, "this")
+	}
+	return ret, nil
+}
+
+func (f *Function) Arity() int {
+	return len(f.params)
+}
+
+// bind returns a copy of f whose closure has "this" defined as inst.
+func (f *Function) bind(inst *Instance) Callable {
+	env := NewEnclosedEnv(f.closure)
+	env.Define("this", inst)
+	bound := *f
+	bound.closure = env
+	return &bound
+}
+
+func (f *Function) String() string {
+	if f.name == "" {
+		return "<fn>"
+	}
+	return "<fn " + f.name + ">"
+}
@@ -0,0 +1,528 @@
+package lox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+)
+
+type Interpreter struct {
+	env     *Env
+	globals *Env
+
+	// frames are the function calls in progress, outermost first.
+	frames []callFrame
+
+	// ctx is the context of the script being run. steps counts the
+	// statements it has executed.
+	ctx   context.Context
+	steps int
+
+	// maxSteps and maxDepth are the limits set by WithMaxSteps and
+	// WithMaxCallDepth, zero when unlimited.
+	maxSteps int
+	maxDepth int
+
+	// backend runs the scripts, vm backs the BytecodeVM backend.
+	backend Backend
+	vm      *vm
+
+	// rand backs the random and seed natives.
+	rand *rand.Rand
+
+	// Streams used by print and the I/O natives.
+	stdout io.Writer
+	stderr io.Writer
+	stdin  *bufio.Reader
+}
+
+func NewInterpreter() *Interpreter {
+	globals := NewEnv()
+	i := &Interpreter{
+		env:      globals,
+		globals:  globals,
+		ctx:      context.Background(),
+		maxDepth: defaultMaxCallDepth,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+		stdin:    bufioReader(os.Stdin),
+	}
+	i.vm = &vm{interp: i}
+	i.defineStdlib()
+	return i
+}
+
+// interpret executes stmts in order and returns the value of the last
+// statement when it is an expression statement. Execution stops early
+// with an error when ctx is done. Each call gets a fresh step budget.
+func (i *Interpreter) interpret(ctx context.Context, stmts []Stmt) (Value, error) {
+	defer i.begin(ctx)()
+
+	var last Value
+	for _, s := range stmts {
+		v, err := i.execute(s)
+		if err != nil {
+			return nil, err
+		}
+		last = nil
+		if _, ok := s.(*ExprStmt); ok {
+			last = v
+		}
+	}
+	return last, nil
+}
+
+func (i *Interpreter) visitAssignExpr(expr *AssignExpr) (Value, error) {
+	val, err := i.eval(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if expr.res.local {
+		i.env.AssignAt(expr.res.depth, expr.Name.Lexeme, val)
+	} else if err := i.globals.Assign(expr.Name.Lexeme, val); err != nil {
+		return nil, i.runtimeError(expr.Name, "undefined variable '%s'", expr.Name.Lexeme)
+	}
+	return val, nil
+}
+
+func (i *Interpreter) visitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	l, err := i.eval(expr.Left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := i.eval(expr.Right)
+	if err != nil {
+		return nil, err
+	}
+	switch expr.Operator.Type {
+	case BangEqual:
+		return !equal(l, r), nil
+	case EqualEqual:
+		return equal(l, r), nil
+	case Plus:
+		v, err := add(l, r)
+		if err != nil {
+			return nil, i.runtimeError(expr.Operator, "%v", err)
+		}
+		return v, nil
+	}
+
+	lf, rf, err := numbers(expr.Operator.Lexeme, l, r)
+	if err != nil {
+		return nil, i.runtimeError(expr.Operator, "%v", err)
+	}
+	switch expr.Operator.Type {
+	case Minus:
+		return lf - rf, nil
+	case Slash:
+		return lf / rf, nil
+	case Star:
+		return lf * rf, nil
+	case Greater:
+		return lf > rf, nil
+	case GreaterEqual:
+		return lf >= rf, nil
+	case Less:
+		return lf < rf, nil
+	case LessEqual:
+		return lf <= rf, nil
+	}
+	return nil, i.runtimeError(expr.Operator, "unknown binary operator: %s", expr.Operator.Lexeme)
+}
+
+func (i *Interpreter) visitCallExpr(expr *CallExpr) (Value, error) {
+	callee, err := i.eval(expr.Callee)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]Value, 0)
+	for _, arg := range expr.Args {
+		a, err := i.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+
+	fn, err := callable(callee)
+	if err != nil {
+		return nil, i.runtimeError(expr.Paren, "%v", err)
+	}
+	if err := checkArity(fn, len(args)); err != nil {
+		return nil, i.runtimeError(expr.Paren, "%v", err)
+	}
+
+	if err := i.pushFrame(fn, expr.Paren); err != nil {
+		return nil, err
+	}
+	defer i.popFrame()
+	v, err := fn.Call(i, args)
+	if err != nil {
+		if _, ok := err.(*RuntimeError); !ok {
+			rerr := i.runtimeError(expr.Paren, "%v", err)
+			rerr.Err = err
+			return nil, rerr
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// calleeName is the name a call to fn shows up as in stack traces.
+func calleeName(fn Callable) string {
+	switch f := fn.(type) {
+	case *Function:
+		if f.name == "" {
+			return "<anonymous>"
+		}
+		return f.name
+	case *closure:
+		if f.proto.name == "" {
+			return "<anonymous>"
+		}
+		return f.proto.name
+	case *boundMethod:
+		return f.method.proto.name
+	case *LoxClass:
+		return f.Name
+	case *NativeFunction:
+		return f.Name
+	}
+	return "native"
+}
+
+func (i *Interpreter) visitGetExpr(expr *GetExpr) (Value, error) {
+	obj, err := i.eval(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	v, err := getProperty(obj, expr.Name.Lexeme)
+	if err != nil {
+		return nil, i.runtimeError(expr.Name, "%v", err)
+	}
+	return v, nil
+}
+
+func (i *Interpreter) visitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	return &Function{params: expr.Params, body: expr.Body, closure: i.env}, nil
+}
+
+func (i *Interpreter) visitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	return i.eval(expr.Expr)
+}
+
+func (i *Interpreter) visitIndexGetExpr(expr *IndexGetExpr) (Value, error) {
+	obj, err := i.eval(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.eval(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	v, err := indexGet(obj, index)
+	if err != nil {
+		return nil, i.runtimeError(expr.Bracket, "%v", err)
+	}
+	return v, nil
+}
+
+func (i *Interpreter) visitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	obj, err := i.eval(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.eval(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	val, err := i.eval(expr.Val)
+	if err != nil {
+		return nil, err
+	}
+	if err := indexSet(obj, index, val); err != nil {
+		return nil, i.runtimeError(expr.Bracket, "%v", err)
+	}
+	return val, nil
+}
+
+func (i *Interpreter) visitListExpr(expr *ListExpr) (Value, error) {
+	elems := make([]Value, 0, len(expr.Elems))
+	for _, e := range expr.Elems {
+		v, err := i.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, v)
+	}
+	return NewList(elems), nil
+}
+
+func (i *Interpreter) visitMapExpr(expr *MapExpr) (Value, error) {
+	m := NewMap()
+	for j := range expr.Keys {
+		k, err := i.eval(expr.Keys[j])
+		if err != nil {
+			return nil, err
+		}
+		v, err := i.eval(expr.Vals[j])
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Set(k, v); err != nil {
+			return nil, i.runtimeError(expr.LBrace, "%v", err)
+		}
+	}
+	return m, nil
+}
+
+func (i *Interpreter) visitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	return expr.Value, nil
+}
+
+func (i *Interpreter) visitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	l, err := i.eval(expr.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Operator.Type == Or {
+		if truthy(l) {
+			return l, nil
+		}
+	} else {
+		if !truthy(l) {
+			return l, nil
+		}
+	}
+	return i.eval(expr.Right)
+}
+
+func (i *Interpreter) visitSetExpr(expr *SetExpr) (Value, error) {
+	obj, err := i.eval(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := fieldsOf(obj)
+	if err != nil {
+		return nil, i.runtimeError(expr.Name, "%v", err)
+	}
+	val, err := i.eval(expr.Val)
+	if err != nil {
+		return nil, err
+	}
+	inst.Set(expr.Name, val)
+	return val, nil
+}
+
+func (i *Interpreter) visitSuperExpr(expr *SuperExpr) (Value, error) {
+	dist := expr.res.depth
+	s, err := i.env.GetAt(dist, "super")
+	if err != nil {
+		return nil, err
+	}
+	superclass := s.(*LoxClass)
+	// "this" is always bound in the scope just inside the one for "super".
+	obj, err := i.env.GetAt(dist-1, "this")
+	if err != nil {
+		return nil, err
+	}
+	method, err := superMethod(superclass, obj.(*Instance), expr.Method.Lexeme)
+	if err != nil {
+		return nil, i.runtimeError(expr.Method, "%v", err)
+	}
+	return method, nil
+}
+
+func (i *Interpreter) visitThisExpr(expr *ThisExpr) (Value, error) {
+	return i.lookUpVar(expr.Keyword, expr.res)
+}
+
+func (i *Interpreter) visitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	r, err := i.eval(expr.Right)
+	if err != nil {
+		return nil, err
+	}
+	switch expr.Operator.Type {
+	case Minus:
+		v, err := negate(r)
+		if err != nil {
+			return nil, i.runtimeError(expr.Operator, "%v", err)
+		}
+		return v, nil
+	case Bang:
+		return !truthy(r), nil
+	}
+	return nil, nil
+}
+
+func (i *Interpreter) visitVarExpr(expr *VarExpr) (Value, error) {
+	return i.lookUpVar(expr.Name, expr.res)
+}
+
+func (i *Interpreter) visitBlockStmt(stmt *BlockStmt) (Value, error) {
+	return nil, i.executeBlock(stmt.Stmts, NewEnclosedEnv(i.env))
+}
+
+func (i *Interpreter) visitClassStmt(stmt *ClassStmt) (Value, error) {
+	var superclass *LoxClass
+	if stmt.Superclass != nil {
+		v, err := i.eval(stmt.Superclass)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(*LoxClass)
+		if !ok {
+			return nil, i.runtimeError(stmt.Superclass.Name, "superclass must be a class")
+		}
+		superclass = s
+	}
+	i.env.Define(stmt.Name.Lexeme, nil)
+
+	enclosing := i.env
+	if superclass != nil {
+		i.env = NewEnclosedEnv(enclosing)
+		i.env.Define("super", superclass)
+	}
+	class := NewLoxClass(stmt.Name.Lexeme, superclass)
+	for _, m := range stmt.Methods {
+		fun := newFunStmt(m, i.env)
+		fun.isInitializer = m.Name.Lexeme == "init"
+		class.methods[m.Name.Lexeme] = fun
+	}
+	i.env = enclosing
+
+	return nil, i.env.Assign(stmt.Name.Lexeme, class)
+}
+
+func (i *Interpreter) visitExprStmt(stmt *ExprStmt) (Value, error) {
+	return i.eval(stmt.Expr)
+}
+func (i *Interpreter) visitFunStmt(stmt *FunStmt) (Value, error) {
+	i.env.Define(stmt.Name.Lexeme, newFunStmt(stmt, i.env))
+	return nil, nil
+}
+func (i *Interpreter) visitIfStmt(stmt *IfStmt) (Value, error) {
+	ok, err := i.eval(stmt.Cond)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(ok) {
+		return i.execute(stmt.Then)
+	} else if stmt.Else != nil {
+		return i.execute(stmt.Else)
+	}
+	return nil, nil
+}
+func (i *Interpreter) visitPrintStmt(stmt *PrintStmt) (Value, error) {
+	v, err := i.eval(stmt.Expr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintln(i.stdout, Stringify(v)); err != nil {
+		return nil, i.runtimeError(stmt.Keyword, "print: %v", err)
+	}
+	return nil, nil
+}
+func (i *Interpreter) visitRetStmt(stmt *RetStmt) (Value, error) {
+	var v Value
+	if stmt.Val != nil {
+		var err error
+		v, err = i.eval(stmt.Val)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, &FunRet{Val: v}
+}
+func (i *Interpreter) visitVarStmt(stmt *VarStmt) error {
+	var v Value
+	var err error
+	if stmt.Init != nil {
+		v, err = i.eval(stmt.Init)
+		if err != nil {
+			return err
+		}
+	}
+	i.env.Define(stmt.Name.Lexeme, v)
+	return nil
+}
+func (i *Interpreter) visitBreakStmt(stmt *BreakStmt) (Value, error) {
+	return nil, errBreak
+}
+
+func (i *Interpreter) visitContinueStmt(stmt *ContinueStmt) (Value, error) {
+	return nil, errContinue
+}
+
+func (i *Interpreter) visitWhileStmt(stmt *WhileStmt) (Value, error) {
+	val, err := i.eval(stmt.Cond)
+	if err != nil {
+		return nil, err
+	}
+	for truthy(val) {
+		if err := i.interrupted(stmt.Keyword); err != nil {
+			return nil, err
+		}
+		if _, err := i.execute(stmt.Body); err == errBreak {
+			break
+		} else if err != nil && err != errContinue {
+			return nil, err
+		}
+		if stmt.Incr != nil {
+			if _, err := i.eval(stmt.Incr); err != nil {
+				return nil, err
+			}
+		}
+		val, err = i.eval(stmt.Cond)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (i *Interpreter) eval(e Expr) (Value, error) {
+	return e.Accept(i)
+}
+
+func (i *Interpreter) execute(s Stmt) (Value, error) {
+	if err := i.step(s); err != nil {
+		return nil, err
+	}
+	return s.Accept(i)
+}
+
+// executeBlock runs stmts with e as the current environment. The caller's
+// environment is restored afterwards, e is not copied, so assignments made
+// through it are visible to everything else sharing it.
+func (i *Interpreter) executeBlock(stmts []Stmt, e *Env) error {
+	prev := i.env
+	i.env = e
+	defer func() { i.env = prev }()
+	for _, s := range stmts {
+		if _, err := i.execute(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookUpVar reads name using where the Resolver found it, or from the
+// globals when it was not resolved to a local.
+func (i *Interpreter) lookUpVar(name Token, res resolution) (Value, error) {
+	if res.local {
+		return i.env.GetAt(res.depth, name.Lexeme)
+	}
+	v, err := i.globals.Get(name.Lexeme)
+	if err != nil {
+		return nil, i.runtimeError(name, "undefined variable '%s'", name.Lexeme)
+	}
+	return v, nil
+}
@@ -0,0 +1,363 @@
+package lox
+
+import "encoding/json"
+
+// The JSON produced by MarshalTokens and MarshalAST is meant for tools not
+// written in Go. Its layout is stable: fields may be added under the same
+// version, anything else bumps jsonVersion.
+//
+// A position is {"line", "column", "offset"}: line and column count from
+// 1, column in bytes, and offset is the byte offset from the start of the
+// source. A span is {"start", "end"} of the source covered, end exclusive,
+// or null for nodes the parser made up, like the condition of "for (;;)".
+//
+// A token is {"type", "lexeme", "span"}, plus "literal" for number and
+// string literals. type is the name of the token type constant, such as
+// "Identifier", "LParen" or "Comment". The token list ends with an "EOF"
+// token with an empty lexeme at the end of the source.
+const jsonVersion = 1
+
+// MarshalTokens returns the tokens and comments scanned by lex as
+//
+//	{"version": 1, "file": ..., "tokens": [token...], "comments": [token...]}
+func MarshalTokens(lex *Lexer) ([]byte, error) {
+	return json.MarshalIndent(map[string]any{
+		"version":  jsonVersion,
+		"file":     lex.File,
+		"tokens":   jsonTokens(lex.Tokens),
+		"comments": jsonTokens(lex.Comments),
+	}, "", "  ")
+}
+
+// MarshalAST returns the syntax tree stmts parsed from file as
+//
+//	{"version": 1, "file": ..., "stmts": [node...]}
+//
+// Every node has a "kind", the name of its Go type, and a "span". The
+// other fields hold tokens, nodes, lists of them, or null when absent:
+//
+//	AssignExpr    name, value
+//	BinaryExpr    left, operator, right
+//	CallExpr      callee, paren, args
+//	FunctionExpr  keyword, params, body
+//	GetExpr       object, name
+//	GroupingExpr  expr
+//	IndexGetExpr  object, index
+//	IndexSetExpr  object, index, value
+//	ListExpr      elems
+//	LiteralExpr   value, a number, string, boolean or null
+//	LogicalExpr   left, operator, right
+//	MapExpr       entries, a list of {"key", "value"}
+//	SetExpr       object, name, value
+//	SuperExpr     method
+//	ThisExpr
+//	UnaryExpr     operator, right
+//	VarExpr       name
+//
+//	BlockStmt     stmts
+//	BreakStmt
+//	ClassStmt     name, superclass, methods
+//	ContinueStmt
+//	ExprStmt      expr
+//	FunStmt       name, params, body
+//	IfStmt        cond, then, else
+//	PrintStmt     expr
+//	RetStmt       value
+//	VarStmt       name, init
+//	WhileStmt     keyword, cond, body, incr
+//
+// For loops appear as the parser desugars them: a WhileStmt whose keyword
+// is "for", inside a BlockStmt without braces if there is an initializer.
+func MarshalAST(file string, stmts []Stmt) ([]byte, error) {
+	return json.MarshalIndent(map[string]any{
+		"version": jsonVersion,
+		"file":    file,
+		"stmts":   jsonStmts(stmts),
+	}, "", "  ")
+}
+
+func jsonPos(p Pos) map[string]any {
+	return map[string]any{"line": p.Line, "column": p.Column, "offset": p.Offset}
+}
+
+func jsonSpan(s Span) any {
+	if !s.IsValid() {
+		return nil
+	}
+	return map[string]any{"start": jsonPos(s.Start), "end": jsonPos(s.End)}
+}
+
+func jsonToken(t Token) any {
+	if !t.Span().IsValid() {
+		return nil
+	}
+	m := map[string]any{
+		"type":   t.Type.String(),
+		"lexeme": t.Lexeme,
+		"span":   jsonSpan(t.Span()),
+	}
+	if t.Literal != nil {
+		m["literal"] = t.Literal
+	}
+	return m
+}
+
+func jsonTokens(ts []Token) []any {
+	out := make([]any, len(ts))
+	for j, t := range ts {
+		out[j] = jsonToken(t)
+	}
+	return out
+}
+
+// jsonNode returns a node of kind covering span with fields.
+func jsonNode(kind string, span Span, fields map[string]any) map[string]any {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	fields["kind"] = kind
+	fields["span"] = jsonSpan(span)
+	return fields
+}
+
+func jsonExpr(e Expr) any {
+	if e == nil {
+		return nil
+	}
+	v, _ := e.Accept(jsonVisitor{})
+	return v
+}
+
+func jsonExprs(es []Expr) []any {
+	out := make([]any, len(es))
+	for j, e := range es {
+		out[j] = jsonExpr(e)
+	}
+	return out
+}
+
+func jsonStmt(s Stmt) any {
+	if s == nil {
+		return nil
+	}
+	// VarStmt.Accept drops what the visitor returns.
+	if v, ok := s.(*VarStmt); ok {
+		return jsonNode("VarStmt", v.Span(), map[string]any{
+			"name": jsonToken(v.Name),
+			"init": jsonExpr(v.Init),
+		})
+	}
+	v, _ := s.Accept(jsonVisitor{})
+	return v
+}
+
+func jsonStmts(ss []Stmt) []any {
+	out := make([]any, len(ss))
+	for j, s := range ss {
+		out[j] = jsonStmt(s)
+	}
+	return out
+}
+
+// jsonVisitor turns nodes into the values MarshalAST encodes.
+type jsonVisitor struct{}
+
+func (jsonVisitor) visitAssignExpr(expr *AssignExpr) (Value, error) {
+	return jsonNode("AssignExpr", expr.Span(), map[string]any{
+		"name":  jsonToken(expr.Name),
+		"value": jsonExpr(expr.Value),
+	}), nil
+}
+
+func (jsonVisitor) visitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	return jsonNode("BinaryExpr", expr.Span(), map[string]any{
+		"left":     jsonExpr(expr.Left),
+		"operator": jsonToken(expr.Operator),
+		"right":    jsonExpr(expr.Right),
+	}), nil
+}
+
+func (jsonVisitor) visitCallExpr(expr *CallExpr) (Value, error) {
+	return jsonNode("CallExpr", expr.Span(), map[string]any{
+		"callee": jsonExpr(expr.Callee),
+		"paren":  jsonToken(expr.Paren),
+		"args":   jsonExprs(expr.Args),
+	}), nil
+}
+
+func (jsonVisitor) visitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	return jsonNode("FunctionExpr", expr.Span(), map[string]any{
+		"keyword": jsonToken(expr.Keyword),
+		"params":  jsonTokens(expr.Params),
+		"body":    jsonStmts(expr.Body),
+	}), nil
+}
+
+func (jsonVisitor) visitGetExpr(expr *GetExpr) (Value, error) {
+	return jsonNode("GetExpr", expr.Span(), map[string]any{
+		"object": jsonExpr(expr.Object),
+		"name":   jsonToken(expr.Name),
+	}), nil
+}
+
+func (jsonVisitor) visitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	return jsonNode("GroupingExpr", expr.Span(), map[string]any{
+		"expr": jsonExpr(expr.Expr),
+	}), nil
+}
+
+func (jsonVisitor) visitIndexGetExpr(expr *IndexGetExpr) (Value, error) {
+	return jsonNode("IndexGetExpr", expr.Span(), map[string]any{
+		"object": jsonExpr(expr.Object),
+		"index":  jsonExpr(expr.Index),
+	}), nil
+}
+
+func (jsonVisitor) visitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	return jsonNode("IndexSetExpr", expr.Span(), map[string]any{
+		"object": jsonExpr(expr.Object),
+		"index":  jsonExpr(expr.Index),
+		"value":  jsonExpr(expr.Val),
+	}), nil
+}
+
+func (jsonVisitor) visitListExpr(expr *ListExpr) (Value, error) {
+	return jsonNode("ListExpr", expr.Span(), map[string]any{
+		"elems": jsonExprs(expr.Elems),
+	}), nil
+}
+
+func (jsonVisitor) visitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	return jsonNode("LiteralExpr", expr.Span(), map[string]any{
+		"value": expr.Value,
+	}), nil
+}
+
+func (jsonVisitor) visitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	return jsonNode("LogicalExpr", expr.Span(), map[string]any{
+		"left":     jsonExpr(expr.Left),
+		"operator": jsonToken(expr.Operator),
+		"right":    jsonExpr(expr.Right),
+	}), nil
+}
+
+func (jsonVisitor) visitMapExpr(expr *MapExpr) (Value, error) {
+	entries := make([]any, len(expr.Keys))
+	for j := range expr.Keys {
+		entries[j] = map[string]any{
+			"key":   jsonExpr(expr.Keys[j]),
+			"value": jsonExpr(expr.Vals[j]),
+		}
+	}
+	return jsonNode("MapExpr", expr.Span(), map[string]any{
+		"entries": entries,
+	}), nil
+}
+
+func (jsonVisitor) visitSetExpr(expr *SetExpr) (Value, error) {
+	return jsonNode("SetExpr", expr.Span(), map[string]any{
+		"object": jsonExpr(expr.Object),
+		"name":   jsonToken(expr.Name),
+		"value":  jsonExpr(expr.Val),
+	}), nil
+}
+
+func (jsonVisitor) visitSuperExpr(expr *SuperExpr) (Value, error) {
+	return jsonNode("SuperExpr", expr.Span(), map[string]any{
+		"method": jsonToken(expr.Method),
+	}), nil
+}
+
+func (jsonVisitor) visitThisExpr(expr *ThisExpr) (Value, error) {
+	return jsonNode("ThisExpr", expr.Span(), nil), nil
+}
+
+func (jsonVisitor) visitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	return jsonNode("UnaryExpr", expr.Span(), map[string]any{
+		"operator": jsonToken(expr.Operator),
+		"right":    jsonExpr(expr.Right),
+	}), nil
+}
+
+func (jsonVisitor) visitVarExpr(expr *VarExpr) (Value, error) {
+	return jsonNode("VarExpr", expr.Span(), map[string]any{
+		"name": jsonToken(expr.Name),
+	}), nil
+}
+
+func (jsonVisitor) visitBlockStmt(stmt *BlockStmt) (Value, error) {
+	return jsonNode("BlockStmt", stmt.Span(), map[string]any{
+		"stmts": jsonStmts(stmt.Stmts),
+	}), nil
+}
+
+func (jsonVisitor) visitBreakStmt(stmt *BreakStmt) (Value, error) {
+	return jsonNode("BreakStmt", stmt.Span(), nil), nil
+}
+
+func (jsonVisitor) visitClassStmt(stmt *ClassStmt) (Value, error) {
+	var superclass any
+	if stmt.Superclass != nil {
+		superclass = jsonExpr(stmt.Superclass)
+	}
+	methods := make([]any, len(stmt.Methods))
+	for j, m := range stmt.Methods {
+		methods[j] = jsonStmt(m)
+	}
+	return jsonNode("ClassStmt", stmt.Span(), map[string]any{
+		"name":       jsonToken(stmt.Name),
+		"superclass": superclass,
+		"methods":    methods,
+	}), nil
+}
+
+func (jsonVisitor) visitContinueStmt(stmt *ContinueStmt) (Value, error) {
+	return jsonNode("ContinueStmt", stmt.Span(), nil), nil
+}
+
+func (jsonVisitor) visitExprStmt(stmt *ExprStmt) (Value, error) {
+	return jsonNode("ExprStmt", stmt.Span(), map[string]any{
+		"expr": jsonExpr(stmt.Expr),
+	}), nil
+}
+
+func (jsonVisitor) visitFunStmt(stmt *FunStmt) (Value, error) {
+	return jsonNode("FunStmt", stmt.Span(), map[string]any{
+		"name":   jsonToken(stmt.Name),
+		"params": jsonTokens(stmt.Params),
+		"body":   jsonStmts(stmt.Body),
+	}), nil
+}
+
+func (jsonVisitor) visitIfStmt(stmt *IfStmt) (Value, error) {
+	return jsonNode("IfStmt", stmt.Span(), map[string]any{
+		"cond": jsonExpr(stmt.Cond),
+		"then": jsonStmt(stmt.Then),
+		"else": jsonStmt(stmt.Else),
+	}), nil
+}
+
+func (jsonVisitor) visitPrintStmt(stmt *PrintStmt) (Value, error) {
+	return jsonNode("PrintStmt", stmt.Span(), map[string]any{
+		"expr": jsonExpr(stmt.Expr),
+	}), nil
+}
+
+func (jsonVisitor) visitRetStmt(stmt *RetStmt) (Value, error) {
+	return jsonNode("RetStmt", stmt.Span(), map[string]any{
+		"value": jsonExpr(stmt.Val),
+	}), nil
+}
+
+func (jsonVisitor) visitVarStmt(stmt *VarStmt) error {
+	return nil
+}
+
+func (jsonVisitor) visitWhileStmt(stmt *WhileStmt) (Value, error) {
+	return jsonNode("WhileStmt", stmt.Span(), map[string]any{
+		"keyword": jsonToken(stmt.Keyword),
+		"cond":    jsonExpr(stmt.Cond),
+		"body":    jsonStmt(stmt.Body),
+		"incr":    jsonExpr(stmt.Incr),
+	}), nil
+}
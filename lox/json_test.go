@@ -0,0 +1,197 @@
+package lox_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"glox/lox"
+)
+
+type jsonPos struct{ Line, Column, Offset int }
+
+type jsonSpan struct{ Start, End jsonPos }
+
+type jsonToken struct {
+	Type    string
+	Lexeme  string
+	Literal any
+	Span    *jsonSpan
+}
+
+func scan(t *testing.T, src string) *lox.Lexer {
+	t.Helper()
+	lex := &lox.Lexer{Source: src, Tokens: []lox.Token{}, File: "t.lox"}
+	if err := lex.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	return lex
+}
+
+func TestMarshalTokens(t *testing.T) {
+	const src = "var x = 1; // one\nprint \"a\";\n"
+	data, err := lox.MarshalTokens(scan(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version  int
+		File     string
+		Tokens   []jsonToken
+		Comments []jsonToken
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 1 || got.File != "t.lox" {
+		t.Errorf("version %d, file %q; want 1, %q", got.Version, got.File, "t.lox")
+	}
+
+	var types []string
+	for _, tok := range got.Tokens {
+		types = append(types, tok.Type)
+	}
+	want := []string{"Var", "Identifier", "Equal", "Number", "Semicolon", "Print", "String", "Semicolon", "EOF"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("token types = %v, want %v", types, want)
+	}
+	if len(got.Tokens) != len(want) {
+		return
+	}
+	if lit := got.Tokens[3].Literal; lit != 1.0 {
+		t.Errorf("number literal = %v, want 1", lit)
+	}
+	if lit := got.Tokens[6].Literal; lit != "a" {
+		t.Errorf("string literal = %v, want a", lit)
+	}
+	if tok := got.Tokens[0]; tok.Literal != nil {
+		t.Errorf("keyword has literal %v", tok.Literal)
+	}
+	if s := got.Tokens[6].Span; s == nil || *s != (jsonSpan{jsonPos{2, 7, 24}, jsonPos{2, 10, 27}}) {
+		t.Errorf("string span = %+v, want 2:7 (24) to 2:10 (27)", s)
+	}
+	eof := got.Tokens[len(got.Tokens)-1]
+	if eof.Lexeme != "" || eof.Span == nil || eof.Span.Start.Offset != len(src) {
+		t.Errorf("EOF token = %+v, want an empty one at offset %d", eof, len(src))
+	}
+
+	if len(got.Comments) != 1 || got.Comments[0].Type != "Comment" || got.Comments[0].Lexeme != "// one" {
+		t.Errorf("comments = %+v, want the one comment", got.Comments)
+	}
+}
+
+func TestMarshalAST(t *testing.T) {
+	lex := scan(t, "var x = 1 + y;\nfor (;;) print x;\n")
+	stmts, err := lox.NewParser(lex.Tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := lox.MarshalAST(lex.File, stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version int
+		File    string
+		Stmts   []map[string]any
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 1 || got.File != "t.lox" || len(got.Stmts) != 2 {
+		t.Fatalf("version %d, file %q, %d stmts; want 1, %q, 2", got.Version, got.File, len(got.Stmts), "t.lox")
+	}
+
+	// path follows keys of nested objects.
+	path := func(node any, keys ...string) any {
+		for _, k := range keys {
+			m, ok := node.(map[string]any)
+			if !ok {
+				return nil
+			}
+			node = m[k]
+		}
+		return node
+	}
+	checks := []struct {
+		node any
+		keys []string
+		want any
+	}{
+		{got.Stmts[0], []string{"kind"}, "VarStmt"},
+		{got.Stmts[0], []string{"name", "lexeme"}, "x"},
+		{got.Stmts[0], []string{"init", "kind"}, "BinaryExpr"},
+		{got.Stmts[0], []string{"init", "operator", "type"}, "Plus"},
+		{got.Stmts[0], []string{"init", "left", "value"}, 1.0},
+		{got.Stmts[0], []string{"init", "right", "name", "lexeme"}, "y"},
+		{got.Stmts[0], []string{"init", "span", "start", "column"}, 9.0},
+		{got.Stmts[0], []string{"init", "span", "end", "column"}, 14.0},
+		{got.Stmts[0], []string{"span", "end", "offset"}, 14.0},
+		{got.Stmts[1], []string{"kind"}, "WhileStmt"},
+		{got.Stmts[1], []string{"keyword", "lexeme"}, "for"},
+		{got.Stmts[1], []string{"cond", "kind"}, "LiteralExpr"},
+		{got.Stmts[1], []string{"cond", "value"}, true},
+		{got.Stmts[1], []string{"incr"}, nil},
+		{got.Stmts[1], []string{"body", "kind"}, "PrintStmt"},
+	}
+	for _, c := range checks {
+		if v := path(c.node, c.keys...); !reflect.DeepEqual(v, c.want) {
+			t.Errorf("%v = %#v, want %#v", c.keys, v, c.want)
+		}
+	}
+	// The condition of "for (;;)" is made up by the parser.
+	if m, ok := path(got.Stmts[1], "cond").(map[string]any); !ok || m["span"] != nil {
+		t.Errorf("made-up condition has span %v, want null", m["span"])
+	}
+}
+
+func TestMarshalASTSamples(t *testing.T) {
+	// Every node of every sample has a kind and a span key.
+	paths, err := filepath.Glob(filepath.Join(sampleDir, "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if _, ok := v["kind"]; ok {
+				if _, ok := v["span"]; !ok {
+					t.Errorf("%s: %v node has no span", path, v["kind"])
+				}
+			}
+			for _, e := range v {
+				walk(path, e)
+			}
+		case []any:
+			for _, e := range v {
+				walk(path, e)
+			}
+		}
+	}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lex := &lox.Lexer{Source: string(src), Tokens: []lox.Token{}, File: path}
+		if lex.Scan() != nil {
+			continue
+		}
+		stmts, err := lox.NewParser(lex.Tokens).Parse()
+		if err != nil {
+			continue
+		}
+		data, err := lox.MarshalAST(path, stmts)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		walk(path, v)
+	}
+}
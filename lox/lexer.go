@@ -0,0 +1,296 @@
+package lox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lexer consumes flat sequence of input.
+// Reads character bar character to create tokens.
+type Lexer struct {
+	Source string
+	Tokens []Token
+
+	// Comments are the comments in Source, in order. They are trivia the
+	// parser does not see, kept for tools like the formatter.
+	Comments []Token
+
+	// File names the source in token positions. It may be empty.
+	File string
+
+	line int
+
+	// start is first character in lexeme
+	start int
+
+	// current is current character in lexeme
+	current int
+
+	// lineStart is the offset of the first character on the current line.
+	lineStart int
+
+	// startLine and startCol locate the first character of the lexeme.
+	startLine int
+	startCol  int
+}
+
+// Scan appends the tokens of Source to Tokens, ending with an EOF token
+// at the end of the source.
+func (l *Lexer) Scan() error {
+	l.current = 0
+	l.start = 0
+	l.line = 1
+	l.lineStart = 0
+	for !l.end() {
+		l.start = l.current
+		l.startLine = l.line
+		l.startCol = l.start - l.lineStart + 1
+		err := l.readToken()
+		if err != nil {
+			return err
+		}
+	}
+	l.start = l.current
+	l.startLine = l.line
+	l.startCol = l.start - l.lineStart + 1
+	l.addToken(EOF, "", nil)
+	return nil
+}
+
+func (l *Lexer) end() bool {
+	return l.current >= len(l.Source)
+}
+
+func (l *Lexer) readToken() error {
+	char := l.Source[l.current]
+	switch char {
+	case '(':
+		l.addToken(LParen, "(", nil)
+		l.current++
+	case ')':
+		l.addToken(RParen, ")", nil)
+		l.current++
+	case '{':
+		l.addToken(LBrace, "{", nil)
+		l.current++
+	case '}':
+		l.addToken(RBrace, "}", nil)
+		l.current++
+	case '[':
+		l.addToken(LBracket, "[", nil)
+		l.current++
+	case ']':
+		l.addToken(RBracket, "]", nil)
+		l.current++
+	case ',':
+		l.addToken(Comma, ",", nil)
+		l.current++
+	case ':':
+		l.addToken(Colon, ":", nil)
+		l.current++
+	case '.':
+		l.addToken(Dot, ".", nil)
+		l.current++
+	case '-':
+		l.addToken(Minus, "-", nil)
+		l.current++
+	case '+':
+		l.addToken(Plus, "+", nil)
+		l.current++
+	case ';':
+		l.addToken(Semicolon, ";", nil)
+		l.current++
+	case '*':
+		l.addToken(Star, "*", nil)
+		l.current++
+	case '!':
+		if l.lookAheadFor('=') {
+			l.addToken(BangEqual, "!=", nil)
+			l.current += 2
+		} else {
+			l.addToken(Bang, "!", nil)
+			l.current++
+		}
+	case '=':
+		if l.lookAheadFor('=') {
+			l.addToken(EqualEqual, "==", nil)
+			l.current += 2
+		} else {
+			l.addToken(Equal, "=", nil)
+			l.current++
+		}
+	case '<':
+		if l.lookAheadFor('=') {
+			l.addToken(LessEqual, "<=", nil)
+			l.current += 2
+		} else {
+			l.addToken(Less, "<", nil)
+			l.current++
+		}
+	case '>':
+		if l.lookAheadFor('=') {
+			l.addToken(GreaterEqual, ">=", nil)
+			l.current += 2
+		} else {
+			l.addToken(Greater, ">", nil)
+			l.current++
+		}
+	case '/':
+		if l.lookAheadFor('/') {
+			// A comment runs until the end of the line.
+			for !l.end() && l.Source[l.current] != '\n' {
+				l.current++
+			}
+			l.Comments = append(l.Comments, Token{
+				Type:   Comment,
+				Lexeme: strings.TrimRight(l.Source[l.start:l.current], " \t\r"),
+				File:   l.File,
+				Line:   l.startLine,
+				Column: l.startCol,
+				Offset: l.start,
+			})
+		} else {
+			l.addToken(Slash, "/", nil)
+			l.current++
+		}
+	case ' ':
+		l.current++
+	case '\r':
+		l.current++
+	case '\t':
+		l.current++
+	case '\n':
+		l.current++
+		l.newline()
+	case '"':
+		return l.str()
+	default: // If not numeric, then identifier.
+		if isNumeric(char) {
+			str, err := l.digit()
+			if err != nil {
+				return l.errorf("erronous number: %v", err)
+			}
+			num, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return l.errorf("erronous number: %v", err)
+			}
+			l.addToken(Number, str, num)
+		} else if isAlpha(char) {
+			str, err := l.identifier()
+			if err != nil {
+				return l.errorf("identifier: %v", err)
+			}
+			if t, ok := Keywords[str]; ok {
+				l.addToken(t, str, nil)
+				break
+			}
+			l.addToken(Identifier, str, nil)
+		} else {
+			return l.errorf("unexpected character %q", char)
+		}
+	}
+	return nil
+}
+
+func (l *Lexer) str() error {
+	l.current++ // opening quote
+	for !l.end() && l.Source[l.current] != '"' {
+		l.current++
+		if l.Source[l.current-1] == '\n' {
+			l.newline()
+		}
+	}
+	if l.end() {
+		return l.errorf("unterminated string")
+	}
+	l.current++ // closing quote
+	lexeme := l.Source[l.start:l.current]
+	l.addToken(String, lexeme, lexeme[1:len(lexeme)-1])
+	return nil
+}
+
+func (l *Lexer) digit() (string, error) {
+	for !l.end() && isNumeric(l.Source[l.current]) {
+		l.current++
+	}
+	if !l.end() && l.Source[l.current] == '.' && l.lookAheadNumeric() {
+		l.current++
+		for !l.end() && isNumeric(l.Source[l.current]) {
+			l.current++
+		}
+	}
+	// An exponent needs a digit after the 'e' and its sign, so "2e" is
+	// still the number 2 followed by the identifier e.
+	if !l.end() && (l.Source[l.current] == 'e' || l.Source[l.current] == 'E') {
+		j := l.current + 1
+		if j < len(l.Source) && (l.Source[j] == '+' || l.Source[j] == '-') {
+			j++
+		}
+		if j < len(l.Source) && isNumeric(l.Source[j]) {
+			l.current = j
+			for !l.end() && isNumeric(l.Source[l.current]) {
+				l.current++
+			}
+		}
+	}
+	return l.Source[l.start:l.current], nil
+}
+
+func (l *Lexer) identifier() (string, error) {
+	for !l.end() && isAlphaNumeric(l.Source[l.current]) {
+		l.current++
+	}
+	return l.Source[l.start:l.current], nil
+}
+
+// lookAheadFor reports whether the character after the current one is want.
+func (l *Lexer) lookAheadFor(want byte) bool {
+	if l.current+1 >= len(l.Source) {
+		return false
+	}
+	return l.Source[l.current+1] == want
+}
+
+func (l *Lexer) lookAheadNumeric() bool {
+	if l.current+1 >= len(l.Source) {
+		return false
+	}
+	return isNumeric(l.Source[l.current+1])
+}
+
+func (l *Lexer) addToken(t TokenType, lexeme string, literal Value) {
+	l.Tokens = append(l.Tokens, Token{
+		Type:    t,
+		Lexeme:  lexeme,
+		Literal: literal,
+		File:    l.File,
+		Line:    l.startLine,
+		Column:  l.startCol,
+		Offset:  l.start,
+	})
+}
+
+// errorf returns an error positioned at the start of the current lexeme.
+func (l *Lexer) errorf(format string, args ...any) error {
+	pos := Pos{File: l.File, Line: l.startLine, Column: l.startCol, Offset: l.start}
+	return fmt.Errorf("[%s] lex error: %s", pos, fmt.Sprintf(format, args...))
+}
+
+// newline is called after consuming a line break.
+func (l *Lexer) newline() {
+	l.line++
+	l.lineStart = l.current
+}
+
+func isAlphaNumeric(ch byte) bool {
+	return isNumeric(ch) || isAlpha(ch)
+}
+
+func isNumeric(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func isAlpha(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+}
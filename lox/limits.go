@@ -0,0 +1,91 @@
+package lox
+
+import (
+	"context"
+	"errors"
+)
+
+// Errors a script is aborted with when it exceeds a limit. They are
+// returned wrapped in a RuntimeError, test for them with errors.Is.
+var (
+	ErrStepLimit = errors.New("step limit exceeded")
+	ErrCallDepth = errors.New("stack overflow")
+)
+
+// defaultMaxCallDepth bounds recursion unless WithMaxCallDepth says
+// otherwise. Every script call nests several Go calls, so without a bound
+// deep recursion overflows the Go stack and crashes the host.
+const defaultMaxCallDepth = 10000
+
+// WithMaxSteps limits how many statements a single Run may execute, or
+// instructions under the BytecodeVM backend. Zero, the default, means no
+// limit.
+func WithMaxSteps(n int) Option {
+	return func(i *Interpreter) {
+		i.maxSteps = n
+	}
+}
+
+// WithMaxCallDepth limits how deeply calls may nest, 10000 by default.
+// Zero means no limit, in which case runaway recursion crashes the host
+// with a Go stack overflow.
+func WithMaxCallDepth(n int) Option {
+	return func(i *Interpreter) {
+		i.maxDepth = n
+	}
+}
+
+// limitError wraps err, one of the limit errors or a context error, in a
+// RuntimeError at tok.
+func (i *Interpreter) limitError(tok Token, err error) *RuntimeError {
+	rerr := i.runtimeError(tok, "%v", err)
+	rerr.Err = err
+	return rerr
+}
+
+// begin makes ctx the context of the script about to run and resets the
+// step budget. The returned func restores the previous context.
+func (i *Interpreter) begin(ctx context.Context) (end func()) {
+	prev := i.ctx
+	i.ctx, i.steps = ctx, 0
+	return func() { i.ctx = prev }
+}
+
+// step counts s, which is about to run, against the step budget.
+func (i *Interpreter) step(s Stmt) error {
+	i.steps++
+	if i.maxSteps > 0 && i.steps > i.maxSteps {
+		start := s.Span().Start
+		tok := Token{File: start.File, Line: start.Line, Column: start.Column, Offset: start.Offset}
+		return i.limitError(tok, ErrStepLimit)
+	}
+	return nil
+}
+
+// interrupted returns an error if the context of the running script is
+// done. It is checked on every loop iteration and call, which is where a
+// script can spend unbounded time.
+func (i *Interpreter) interrupted(tok Token) error {
+	if err := i.ctx.Err(); err != nil {
+		return i.limitError(tok, err)
+	}
+	return nil
+}
+
+// pushFrame records a call to fn made at site, after checking that the
+// script may still make it. Every successful pushFrame must be followed by
+// a popFrame.
+func (i *Interpreter) pushFrame(fn Callable, site Token) error {
+	if err := i.interrupted(site); err != nil {
+		return err
+	}
+	if i.maxDepth > 0 && len(i.frames) >= i.maxDepth {
+		return i.limitError(site, ErrCallDepth)
+	}
+	i.frames = append(i.frames, callFrame{name: calleeName(fn), site: site})
+	return nil
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
@@ -0,0 +1,102 @@
+package lox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"glox/lox"
+)
+
+func TestMaxSteps(t *testing.T) {
+	for _, backend := range backends {
+		i := lox.New(lox.WithBackend(backend), lox.WithMaxSteps(1000))
+		_, err := i.Run(context.Background(), `while (true) {}`)
+		var rerr *lox.RuntimeError
+		if !errors.As(err, &rerr) || !errors.Is(err, lox.ErrStepLimit) {
+			t.Errorf("%v: error = %v, want a RuntimeError wrapping ErrStepLimit", backend, err)
+		}
+
+		// Every run gets the whole budget again.
+		for n := 0; n < 3; n++ {
+			if _, err := i.Run(context.Background(), `var j = 0; while (j < 10) j = j + 1;`); err != nil {
+				t.Errorf("%v: run %d: %v", backend, n, err)
+			}
+		}
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	for _, backend := range backends {
+		i := lox.New(lox.WithBackend(backend), lox.WithMaxCallDepth(50))
+		_, err := i.Run(context.Background(), `fun f(n) { return f(n + 1); } f(0);`)
+		if !errors.Is(err, lox.ErrCallDepth) {
+			t.Errorf("%v: error = %v, want ErrCallDepth", backend, err)
+		}
+		if _, err := i.Run(context.Background(), `fun g(n) { if (n > 0) g(n - 1); } g(40);`); err != nil {
+			t.Errorf("%v: %v", backend, err)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	for _, backend := range backends {
+		ctx, cancel := context.WithCancel(context.Background())
+		i := lox.New(lox.WithBackend(backend), lox.WithNative("stop", 0, func(*lox.Interpreter, []lox.Value) (lox.Value, error) {
+			cancel()
+			return nil, nil
+		}))
+		_, err := i.Run(ctx, `stop(); while (true) {}`)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%v: error = %v, want context.Canceled", backend, err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err = i.Run(ctx, `fun spin() { while (true) {} } spin();`)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%v: error = %v, want context.DeadlineExceeded", backend, err)
+		}
+
+		// The interpreter keeps working after a cancelled run.
+		if _, err := i.Run(context.Background(), `var ok = true;`); err != nil {
+			t.Errorf("%v: %v", backend, err)
+		}
+	}
+}
+
+func TestCancelBeforeRun(t *testing.T) {
+	// A context done before the run starts runs nothing and fails like a
+	// cancel during the run.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	check := func(name string, out *bytes.Buffer, err error) {
+		var rerr *lox.RuntimeError
+		if !errors.As(err, &rerr) || !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: error = %#v, want a RuntimeError wrapping context.Canceled", name, err)
+		} else if !strings.HasPrefix(rerr.Error(), "[t.lox:1:1] runtime error: context canceled") {
+			t.Errorf("%s: error = %q, want it at t.lox:1:1", name, rerr)
+		}
+		if out.Len() > 0 {
+			t.Errorf("%s: printed %q", name, out)
+		}
+	}
+	for _, backend := range backends {
+		var out bytes.Buffer
+		i := lox.New(lox.WithBackend(backend), lox.WithStdout(&out))
+		_, err := i.RunFile(ctx, "t.lox", `print "unreachable";`)
+		check(backend.String(), &out, err)
+	}
+
+	var out bytes.Buffer
+	i := lox.New(lox.WithStdout(&out))
+	prog, err := i.Compile("t.lox", `print "unreachable";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = i.RunProgram(ctx, prog)
+	check("RunProgram", &out, err)
+}
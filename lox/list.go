@@ -0,0 +1,56 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// List is a growable, mutable sequence of values. Lists are shared by
+// reference, like instances.
+type List struct {
+	Elems []Value
+}
+
+func NewList(elems []Value) *List {
+	return &List{Elems: elems}
+}
+
+// index turns a Lox index into a position in l.Elems. Negative indexes
+// count from the end, so -1 is the last element.
+func (l *List) index(v Value) (int, error) {
+	f, ok := v.(float64)
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("list index must be an integer, got: %s", Stringify(v))
+	}
+	j, ok := toInt(f)
+	if ok && j < 0 {
+		j += len(l.Elems)
+	}
+	if !ok || j < 0 || j >= len(l.Elems) {
+		return 0, fmt.Errorf("list index %s out of range for length %d", Stringify(f), len(l.Elems))
+	}
+	return j, nil
+}
+
+func (l *List) String() string {
+	var b strings.Builder
+	l.write(&b, nil)
+	return b.String()
+}
+
+func (l *List) write(b *strings.Builder, enclosing []Value) {
+	if contains(enclosing, l) {
+		b.WriteString("[...]")
+		return
+	}
+	enclosing = append(enclosing, l)
+	b.WriteByte('[')
+	for j, e := range l.Elems {
+		if j > 0 {
+			b.WriteString(", ")
+		}
+		writeRepr(b, e, enclosing)
+	}
+	b.WriteByte(']')
+}
@@ -0,0 +1,163 @@
+// Package lox implements the Lox language from Nystrom's Crafting
+// Interpreters as an embeddable interpreter. Scripts run on a tree-walker
+// or, with WithBackend(BytecodeVM), are compiled to bytecode for a stack
+// machine.
+//
+// A host program creates an Interpreter with New and feeds it source code
+// with Run. Global state survives between calls, so the same Interpreter
+// can back a REPL or be fed a script piece by piece.
+package lox
+
+import (
+	"context"
+	"fmt"
+)
+
+// Option configures an Interpreter created by New.
+type Option func(*Interpreter)
+
+// WithGlobal defines name as a global variable holding v.
+func WithGlobal(name string, v Value) Option {
+	return func(i *Interpreter) {
+		i.globals.Define(name, v)
+	}
+}
+
+// WithArgs defines the global args as a list of the strings in args, the
+// command-line arguments of the script.
+func WithArgs(args []string) Option {
+	return func(i *Interpreter) {
+		elems := make([]Value, len(args))
+		for j, a := range args {
+			elems[j] = a
+		}
+		i.globals.Define("args", NewList(elems))
+	}
+}
+
+// Backend selects how an Interpreter executes scripts.
+type Backend int
+
+const (
+	// TreeWalker evaluates the syntax tree directly. It is the default.
+	TreeWalker Backend = iota
+	// BytecodeVM compiles scripts to bytecode and runs them on a stack
+	// machine.
+	BytecodeVM
+)
+
+func (b Backend) String() string {
+	if b == BytecodeVM {
+		return "vm"
+	}
+	return "tree"
+}
+
+// ParseBackend returns the backend named s, "tree" or "vm".
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "tree":
+		return TreeWalker, nil
+	case "vm":
+		return BytecodeVM, nil
+	}
+	return 0, fmt.Errorf("unknown backend %q", s)
+}
+
+// WithBackend selects the backend scripts run on.
+func WithBackend(b Backend) Option {
+	return func(i *Interpreter) {
+		i.backend = b
+	}
+}
+
+// New returns an Interpreter configured by opts.
+func New(opts ...Option) *Interpreter {
+	interp := NewInterpreter()
+	for _, opt := range opts {
+		opt(interp)
+	}
+	return interp
+}
+
+// CompileError is returned when source can not be scanned, parsed,
+// resolved or compiled to bytecode. None of the source has been executed when it is returned.
+type CompileError struct {
+	Err error
+}
+
+func (e *CompileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
+
+// Run executes source. The returned Value is the value of the last
+// statement if that is an expression statement, nil otherwise.
+//
+// Cancelling ctx stops the script at its next loop iteration or call
+// with a RuntimeError wrapping ctx.Err().
+func (i *Interpreter) Run(ctx context.Context, source string) (Value, error) {
+	return i.RunFile(ctx, "", source)
+}
+
+// RunFile is like Run, but positions in errors name filename.
+func (i *Interpreter) RunFile(ctx context.Context, filename, source string) (Value, error) {
+	stmts, err := i.parse(filename, source)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, i.limitError(Token{File: filename, Line: 1, Column: 1}, err)
+	}
+	return i.run(ctx, filename, stmts)
+}
+
+// parse scans, parses and resolves source, returning problems as a
+// CompileError.
+func (i *Interpreter) parse(filename, source string) ([]Stmt, error) {
+	lex := Lexer{Source: source, Tokens: []Token{}, File: filename}
+	if err := lex.Scan(); err != nil {
+		return nil, &CompileError{err}
+	}
+	stmts, err := NewParser(lex.Tokens).Parse()
+	if err != nil {
+		return nil, &CompileError{err}
+	}
+	if err := NewResolver().Resolve(stmts); err != nil {
+		return nil, &CompileError{err}
+	}
+	return stmts, nil
+}
+
+// Eval evaluates a single expression, such as "a + 1", against the
+// current global state.
+func (i *Interpreter) Eval(expr string) (Value, error) {
+	lex := Lexer{Source: expr, Tokens: []Token{}}
+	if err := lex.Scan(); err != nil {
+		return nil, &CompileError{err}
+	}
+	e, err := NewParser(lex.Tokens).ParseExpr()
+	if err != nil {
+		return nil, &CompileError{err}
+	}
+	stmts := []Stmt{&ExprStmt{Expr: e}}
+	if err := NewResolver().Resolve(stmts); err != nil {
+		return nil, &CompileError{err}
+	}
+	return i.run(context.Background(), "", stmts)
+}
+
+// run executes resolved stmts from file on the configured backend.
+func (i *Interpreter) run(ctx context.Context, file string, stmts []Stmt) (Value, error) {
+	if i.backend != BytecodeVM {
+		return i.interpret(ctx, stmts)
+	}
+	proto, err := compile(stmts, file)
+	if err != nil {
+		return nil, &CompileError{err}
+	}
+	return i.vm.exec(ctx, proto)
+}
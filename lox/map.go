@@ -0,0 +1,101 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Map is a mutable mapping from strings and numbers to values. It
+// remembers insertion order, so printing and keys() are deterministic.
+// Maps are shared by reference, like lists.
+type Map struct {
+	keys []Value
+	vals map[Value]Value
+}
+
+func NewMap() *Map {
+	return &Map{vals: make(map[Value]Value)}
+}
+
+// checkKey reports whether v can be used as a map key.
+func checkKey(v Value) error {
+	switch k := v.(type) {
+	case string:
+		return nil
+	case float64:
+		if math.IsNaN(k) {
+			return fmt.Errorf("map key can not be NaN")
+		}
+		return nil
+	}
+	return fmt.Errorf("map keys must be strings or numbers, got: %s", typeName(v))
+}
+
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys in insertion order.
+func (m *Map) Keys() []Value {
+	keys := make([]Value, len(m.keys))
+	copy(keys, m.keys)
+	return keys
+}
+
+func (m *Map) Get(key Value) (Value, bool) {
+	v, ok := m.vals[key]
+	return v, ok
+}
+
+// Set adds or replaces the value for key. A replaced key keeps its
+// original position.
+func (m *Map) Set(key, val Value) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if _, ok := m.vals[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.vals[key] = val
+	return nil
+}
+
+// Delete removes key and reports whether it was present.
+func (m *Map) Delete(key Value) bool {
+	if _, ok := m.vals[key]; !ok {
+		return false
+	}
+	delete(m.vals, key)
+	for j, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:j], m.keys[j+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (m *Map) String() string {
+	var b strings.Builder
+	m.write(&b, nil)
+	return b.String()
+}
+
+func (m *Map) write(b *strings.Builder, enclosing []Value) {
+	if contains(enclosing, m) {
+		b.WriteString("{...}")
+		return
+	}
+	enclosing = append(enclosing, m)
+	b.WriteByte('{')
+	for j, k := range m.keys {
+		if j > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(repr(k))
+		b.WriteString(": ")
+		writeRepr(b, m.vals[k], enclosing)
+	}
+	b.WriteByte('}')
+}
@@ -0,0 +1,133 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+)
+
+// NativeFn is the Go implementation of a NativeFunction. It is called with
+// exactly as many arguments as the function's arity.
+type NativeFn func(interp *Interpreter, args []Value) (Value, error)
+
+// NativeFunction is a function implemented by the host and callable from
+// scripts like any other function.
+type NativeFunction struct {
+	Name  string
+	arity int
+	fn    NativeFn
+}
+
+func NewNativeFunction(name string, arity int, fn NativeFn) *NativeFunction {
+	return &NativeFunction{Name: name, arity: arity, fn: fn}
+}
+
+func (n *NativeFunction) Call(interp *Interpreter, args []Value) (Value, error) {
+	return n.fn(interp, args)
+}
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn " + n.Name + ">"
+}
+
+// Define binds name to v in the global environment, replacing any
+// existing global of that name.
+func (i *Interpreter) Define(name string, v Value) {
+	i.globals.Define(name, v)
+}
+
+// DefineNative makes fn callable from scripts as the global function name.
+func (i *Interpreter) DefineNative(name string, arity int, fn NativeFn) {
+	i.Define(name, NewNativeFunction(name, arity, fn))
+}
+
+// WithNative defines a native function, see DefineNative.
+func WithNative(name string, arity int, fn NativeFn) Option {
+	return func(i *Interpreter) {
+		i.DefineNative(name, arity, fn)
+	}
+}
+
+// Call calls fn, which must be Callable, with args. Natives use it to
+// call back into script functions; hosts can use it to call functions a
+// script defined.
+func (i *Interpreter) Call(fn Value, args ...Value) (Value, error) {
+	callee, err := callable(fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkArity(callee, len(args)); err != nil {
+		return nil, err
+	}
+	// The call site is wherever the innermost call in progress was made.
+	var site Token
+	if len(i.frames) > 0 {
+		site = i.frames[len(i.frames)-1].site
+	}
+	if err := i.pushFrame(callee, site); err != nil {
+		return nil, err
+	}
+	defer i.popFrame()
+	return callee.Call(i, args)
+}
+
+// argError reports a badly typed argument to a native function. The
+// position j counts from 0.
+func argError(fn string, j int, want string, got Value) error {
+	return fmt.Errorf("%s: argument %d must be %s, got: %s", fn, j+1, want, typeName(got))
+}
+
+func numberArg(fn string, args []Value, j int) (float64, error) {
+	f, ok := args[j].(float64)
+	if !ok {
+		return 0, argError(fn, j, "a number", args[j])
+	}
+	return f, nil
+}
+
+func intArg(fn string, args []Value, j int) (int, error) {
+	f, ok := args[j].(float64)
+	if !ok || f != math.Trunc(f) {
+		return 0, argError(fn, j, "an integer", args[j])
+	}
+	n, ok := toInt(f)
+	if !ok {
+		return 0, fmt.Errorf("%s: argument %d out of range, got: %s", fn, j+1, Stringify(f))
+	}
+	return n, nil
+}
+
+// toInt returns f as an int if f is an integer that fits in one.
+func toInt(f float64) (int, bool) {
+	if f != math.Trunc(f) || f < math.MinInt || f >= -math.MinInt {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func listArg(fn string, args []Value, j int) (*List, error) {
+	l, ok := args[j].(*List)
+	if !ok {
+		return nil, argError(fn, j, "a list", args[j])
+	}
+	return l, nil
+}
+
+func mapArg(fn string, args []Value, j int) (*Map, error) {
+	m, ok := args[j].(*Map)
+	if !ok {
+		return nil, argError(fn, j, "a map", args[j])
+	}
+	return m, nil
+}
+
+func stringArg(fn string, args []Value, j int) (string, error) {
+	s, ok := args[j].(string)
+	if !ok {
+		return "", argError(fn, j, "a string", args[j])
+	}
+	return s, nil
+}
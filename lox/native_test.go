@@ -0,0 +1,41 @@
+package lox_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntegerArgs(t *testing.T) {
+	tests := []struct {
+		src, want, err string
+	}{
+		{src: `print slice([1, 2, 3], 1, -1);`, want: "[2]"},
+		{src: `print slice([1, 2], 0, pow(10, 20));`, err: "slice: argument 3 out of range"},
+		{src: `print slice([1, 2], -pow(2, 64), 1);`, err: "slice: argument 2 out of range"},
+		{src: `print slice([1, 2], 0, 1.5);`, err: "slice: argument 3 must be an integer"},
+		{src: `print substr("abc", 0, 1 / 0);`, err: "substr: argument 3 out of range"},
+		{src: `print substr("abc", 0 / 0, 1);`, err: "substr: argument 2 must be an integer"},
+		{src: `seed(pow(2, 64));`, err: "seed: argument 1 out of range"},
+		{src: `print [1, 2][-2];`, want: "1"},
+		{src: `print [1, 2][pow(10, 20)];`, err: "list index 100000000000000000000 out of range for length 2"},
+		{src: `print [1, 2][-pow(2, 63)];`, err: "out of range for length 2"},
+		{src: `var l = [1]; l[1 / 0] = 2;`, err: "out of range for length 1"},
+		{src: `print [1, 2][0.5];`, err: "list index must be an integer"},
+	}
+	for _, backend := range backends {
+		for _, tt := range tests {
+			out, err := runSource(backend, tt.src)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("%v: %s: error = %v, want one containing %q", backend, tt.src, err, tt.err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%v: %s: %v", backend, tt.src, err)
+			} else if got := strings.TrimSuffix(out, "\n"); got != tt.want {
+				t.Errorf("%v: %s: printed %q, want %q", backend, tt.src, got, tt.want)
+			}
+		}
+	}
+}
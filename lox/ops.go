@@ -0,0 +1,122 @@
+package lox
+
+import "fmt"
+
+// The operations below are shared by the tree-walker and the VM so that
+// both backends agree on semantics and error messages. Errors are plain
+// errors; callers turn them into a RuntimeError at the right position.
+
+// add implements '+', which adds numbers and concatenates strings.
+func add(l, r Value) (Value, error) {
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return ls + rs, nil
+		}
+	}
+	if lf, ok := l.(float64); ok {
+		if rf, ok := r.(float64); ok {
+			return lf + rf, nil
+		}
+	}
+	return nil, fmt.Errorf("operands of '+' must be two numbers or two strings, got: %s and %s", typeName(l), typeName(r))
+}
+
+// numbers checks the operands of the arithmetic and comparison operator
+// op, other than '+'.
+func numbers(op string, l, r Value) (float64, float64, error) {
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return 0, 0, fmt.Errorf("operands of '%s' must be numbers, got: %s and %s", op, typeName(l), typeName(r))
+	}
+	return lf, rf, nil
+}
+
+func negate(v Value) (Value, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("operand of '-' must be a number, got: %s", typeName(v))
+	}
+	return -f, nil
+}
+
+func indexGet(obj, index Value) (Value, error) {
+	switch obj := obj.(type) {
+	case *List:
+		j, err := obj.index(index)
+		if err != nil {
+			return nil, err
+		}
+		return obj.Elems[j], nil
+	case *Map:
+		v, ok := obj.Get(index)
+		if !ok {
+			return nil, fmt.Errorf("undefined map key %s", repr(index))
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("only lists and maps can be indexed, got: %s", typeName(obj))
+}
+
+func indexSet(obj, index, val Value) error {
+	switch obj := obj.(type) {
+	case *List:
+		j, err := obj.index(index)
+		if err != nil {
+			return err
+		}
+		obj.Elems[j] = val
+		return nil
+	case *Map:
+		return obj.Set(index, val)
+	}
+	return fmt.Errorf("only lists and maps can be indexed, got: %s", typeName(obj))
+}
+
+// getProperty returns the field or bound method name of obj.
+func getProperty(obj Value, name string) (Value, error) {
+	inst, ok := obj.(*Instance)
+	if !ok {
+		return nil, fmt.Errorf("only instances have properties, got: %s", typeName(obj))
+	}
+	v, ok := inst.get(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined property '%s'", name)
+	}
+	return v, nil
+}
+
+// fieldsOf returns obj as an instance whose fields can be set.
+func fieldsOf(obj Value) (*Instance, error) {
+	inst, ok := obj.(*Instance)
+	if !ok {
+		return nil, fmt.Errorf("only instances have fields, got: %s", typeName(obj))
+	}
+	return inst, nil
+}
+
+// superMethod returns the method name of superclass bound to this.
+func superMethod(superclass *LoxClass, this *Instance, name string) (Value, error) {
+	m := superclass.findMethod(name)
+	if m == nil {
+		return nil, fmt.Errorf("undefined property '%s'", name)
+	}
+	return m.bind(this), nil
+}
+
+// checkArity reports a call to fn with the wrong number of arguments.
+func checkArity(fn Callable, argc int) error {
+	if argc != fn.Arity() {
+		return fmt.Errorf("expected %d arguments but got %d", fn.Arity(), argc)
+	}
+	return nil
+}
+
+// callable returns v as a Callable, if it can be called.
+func callable(v Value) (Callable, error) {
+	fn, ok := v.(Callable)
+	if !ok {
+		return nil, fmt.Errorf("can only call functions and classes, got: %s", typeName(v))
+	}
+	return fn, nil
+}
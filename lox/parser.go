@@ -0,0 +1,876 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxArgs is the most parameters a function may declare, or arguments a
+// call may pass.
+const maxArgs = 255
+
+type Parser struct {
+	tokens []Token
+	curr   int
+	errs   []error
+}
+
+// NewParser returns a parser for tokens, which normally end with the EOF
+// token added by Lexer.Scan. One is made up if they do not.
+func NewParser(tokens []Token) *Parser {
+	if n := len(tokens); n == 0 || tokens[n-1].Type != EOF {
+		eof := Token{Type: EOF, Line: 1, Column: 1}
+		if n > 0 {
+			end := tokens[n-1].Span().End
+			eof = Token{Type: EOF, File: end.File, Line: end.Line, Column: end.Column, Offset: end.Offset}
+		}
+		tokens = append(tokens[:n:n], eof)
+	}
+	return &Parser{tokens: tokens}
+}
+
+// ParseError is a syntax error at Token.
+type ParseError struct {
+	Token Token
+	Msg   string
+}
+
+func (e *ParseError) Error() string {
+	if e.Token.Type == EOF {
+		return fmt.Sprintf("[%s] parse error: unexpected end of input, %s", e.Token.Pos(), e.Msg)
+	}
+	return fmt.Sprintf("[%s] parse error at '%s': %s", e.Token.Pos(), e.Token.Lexeme, e.Msg)
+}
+
+// Parse parses all declarations in the token stream. On a syntax error the
+// parser skips ahead to the next statement boundary and carries on, so
+// every error in the input is returned, joined together.
+func (p *Parser) Parse() ([]Stmt, error) {
+	stmts := make([]Stmt, 0)
+	p.errs = nil
+	for !p.end() {
+		if s := p.declaration(); s != nil {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts, errors.Join(p.errs...)
+}
+
+// ParseExpr parses the tokens as a single expression.
+func (p *Parser) ParseExpr() (Expr, error) {
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.end() {
+		return nil, p.error(p.peek(), "expected end of expression")
+	}
+	return e, nil
+}
+
+// synchronize discards tokens until it is probably at the start of the
+// next statement.
+func (p *Parser) synchronize() {
+	p.step()
+	for !p.end() {
+		if p.previous().Type == Semicolon {
+			return
+		}
+		switch p.peek().Type {
+		case Class, Fun, Var, For, If, While, Print, Return, Break, Continue:
+			return
+		}
+		p.step()
+	}
+}
+
+// declaration parses a declaration. On a syntax error it records the
+// error, skips to the next statement boundary and returns nil, so an error
+// inside a block or function body is recovered from in place.
+func (p *Parser) declaration() Stmt {
+	s, err := p.decl()
+	if err != nil {
+		p.errs = append(p.errs, err)
+		p.synchronize()
+		return nil
+	}
+	return s
+}
+
+func (p *Parser) decl() (Stmt, error) {
+	if p.match(Class) {
+		p.step()
+		return p.classDeclaration()
+	}
+	if p.match(Var) {
+		p.step()
+		return p.varDeclaration()
+	}
+	// Without a name, fun starts an anonymous function expression.
+	if p.match(Fun) && p.nextIs(Identifier) {
+		p.step()
+		fun, err := p.funDeclaration("function")
+		if err != nil {
+			return nil, err
+		}
+		return fun, nil
+	}
+	return p.stmt()
+}
+
+func (p *Parser) classDeclaration() (Stmt, error) {
+	kw := p.previous()
+	name, err := p.consume(Identifier, "expected class name")
+	if err != nil {
+		return nil, err
+	}
+
+	var superclass *VarExpr
+	if p.match(Less) {
+		p.step()
+		super, err := p.consume(Identifier, "expected superclass name after '<'")
+		if err != nil {
+			return nil, err
+		}
+		superclass = &VarExpr{Name: super}
+	}
+
+	if _, err := p.consume(LBrace, "expected '{' before class body"); err != nil {
+		return nil, err
+	}
+	methods := make([]*FunStmt, 0)
+	for !p.match(RBrace) && !p.end() {
+		m, err := p.funDeclaration("method")
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, m)
+	}
+	rbrace, err := p.consume(RBrace, "expected '}' after class body")
+	if err != nil {
+		return nil, err
+	}
+	return &ClassStmt{
+		Keyword:    kw,
+		Name:       name,
+		Superclass: superclass,
+		Methods:    methods,
+		RBrace:     rbrace,
+	}, nil
+}
+
+func (p *Parser) varDeclaration() (Stmt, error) {
+	kw := p.previous()
+	name, err := p.consume(Identifier, "expected variable name")
+	if err != nil {
+		return nil, err
+	}
+	var init Expr
+	if p.match(Equal) {
+		p.step()
+		init, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	semi, err := p.consume(Semicolon, "expected ';' after variable declaration")
+	if err != nil {
+		return nil, err
+	}
+	return &VarStmt{Keyword: kw, Name: name, Init: init, Semicolon: semi}, nil
+}
+
+func (p *Parser) funDeclaration(kind string) (*FunStmt, error) {
+	var kw Token
+	if kind == "function" {
+		kw = p.previous()
+	}
+	name, err := p.consume(Identifier, "expected "+kind+" name")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(LParen, "expected '(' after "+kind+" name"); err != nil {
+		return nil, err
+	}
+	params, bod, err := p.funBody(kind)
+	if err != nil {
+		return nil, err
+	}
+	return &FunStmt{
+		Keyword: kw,
+		Name:    name,
+		Params:  params,
+		Body:    bod.Stmts,
+		RBrace:  bod.RBrace,
+	}, nil
+}
+
+// funExpr parses an anonymous function, after the 'fun' keyword.
+func (p *Parser) funExpr() (Expr, error) {
+	kw := p.previous()
+	if _, err := p.consume(LParen, "expected '(' after 'fun'"); err != nil {
+		return nil, err
+	}
+	params, bod, err := p.funBody("function")
+	if err != nil {
+		return nil, err
+	}
+	return &FunctionExpr{
+		Keyword: kw,
+		Params:  params,
+		Body:    bod.Stmts,
+		RBrace:  bod.RBrace,
+	}, nil
+}
+
+// funBody parses the parameters and body of a function, after the '('.
+func (p *Parser) funBody(kind string) ([]Token, *BlockStmt, error) {
+	params := make([]Token, 0)
+	if !p.match(RParen) {
+		for {
+			if len(params) >= maxArgs {
+				return nil, nil, p.error(p.peek(), fmt.Sprintf("can not have more than %d parameters", maxArgs))
+			}
+			ident, err := p.consume(Identifier, "expected parameter name")
+			if err != nil {
+				return nil, nil, err
+			}
+			params = append(params, ident)
+			if !p.match(Comma) {
+				break
+			}
+			p.step()
+		}
+	}
+	if _, err := p.consume(RParen, "expected ')' after parameters"); err != nil {
+		return nil, nil, err
+	}
+
+	if !p.match(LBrace) {
+		return nil, nil, p.error(p.peek(), "expected '{' before "+kind+" body")
+	}
+	bod, err := p.block()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return params, bod, nil
+}
+
+func (p *Parser) stmt() (Stmt, error) {
+	if p.match(Print) {
+		p.step()
+		return p.printStmt()
+	}
+	if p.match(Return) {
+		p.step()
+		return p.retStmt()
+	}
+	if p.match(While) {
+		p.step()
+		return p.whileStmt()
+	}
+	if p.match(For) {
+		p.step()
+		return p.forStmt()
+	}
+	if p.match(LBrace) && !p.mapAhead() {
+		return p.block()
+	}
+	if p.match(If) {
+		p.step()
+		return p.ifStmt()
+	}
+	if p.match(Break) {
+		p.step()
+		kw := p.previous()
+		semi, err := p.consume(Semicolon, "expected ';' after 'break'")
+		if err != nil {
+			return nil, err
+		}
+		return &BreakStmt{Keyword: kw, Semicolon: semi}, nil
+	}
+	if p.match(Continue) {
+		p.step()
+		kw := p.previous()
+		semi, err := p.consume(Semicolon, "expected ';' after 'continue'")
+		if err != nil {
+			return nil, err
+		}
+		return &ContinueStmt{Keyword: kw, Semicolon: semi}, nil
+	}
+	return p.exprStmt()
+}
+
+func (p *Parser) retStmt() (Stmt, error) {
+	kw := p.previous()
+	var val Expr
+	var err error
+	if !p.match(Semicolon) {
+		val, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	semi, err := p.consume(Semicolon, "expected ';' after return value")
+	if err != nil {
+		return nil, err
+	}
+	return &RetStmt{
+		Keyword:   kw,
+		Val:       val,
+		Semicolon: semi,
+	}, nil
+}
+
+func (p *Parser) whileStmt() (Stmt, error) {
+	kw := p.previous()
+	if _, err := p.consume(LParen, "expected '(' after 'while'"); err != nil {
+		return nil, err
+	}
+	cond, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(RParen, "expected ')' after condition"); err != nil {
+		return nil, err
+	}
+	bod, err := p.stmt()
+	if err != nil {
+		return nil, err
+	}
+	return &WhileStmt{Keyword: kw, Cond: cond, Body: bod}, nil
+}
+
+func (p *Parser) forStmt() (Stmt, error) {
+	kw := p.previous()
+	if _, err := p.consume(LParen, "expected '(' after 'for'"); err != nil {
+		return nil, err
+	}
+	var err error
+	var initialiser Stmt
+	if p.match(Semicolon) {
+		p.step()
+		initialiser = nil
+	} else if p.match(Var) {
+		p.step()
+		initialiser, err = p.varDeclaration()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		initialiser, err = p.exprStmt()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var cond Expr = nil
+	if !p.match(Semicolon) {
+		cond, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(Semicolon, "expected ';' after loop condition"); err != nil {
+		return nil, err
+	}
+
+	var incr Expr = nil
+	if !p.match(RParen) {
+		incr, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(RParen, "expected ')' after for clauses"); err != nil {
+		return nil, err
+	}
+
+	bod, err := p.stmt()
+	if err != nil {
+		return nil, err
+	}
+
+	if cond == nil {
+		cond = &LiteralExpr{Value: true}
+	}
+	// The increment is kept out of the body so that continue, which
+	// skips the rest of the body, still runs it.
+	bod = &WhileStmt{Keyword: kw, Cond: cond, Body: bod, Incr: incr}
+
+	if initialiser != nil {
+		stmts := []Stmt{initialiser, bod}
+		bod = &BlockStmt{Stmts: stmts}
+	}
+	return bod, nil
+}
+
+func (p *Parser) ifStmt() (Stmt, error) {
+	kw := p.previous()
+	if _, err := p.consume(LParen, "expected '(' after 'if'"); err != nil {
+		return nil, err
+	}
+	cond, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(RParen, "expected ')' after if condition"); err != nil {
+		return nil, err
+	}
+	thenBranch, err := p.stmt()
+	if err != nil {
+		return nil, err
+	}
+	var elseBranch Stmt
+	elseBranch = nil
+	if p.match(Else) {
+		p.step()
+		elseBranch, err = p.stmt()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &IfStmt{Keyword: kw, Cond: cond, Then: thenBranch, Else: elseBranch}, nil
+}
+
+// block parses the statements between a pair of braces. The current
+// token must be the opening '{'.
+func (p *Parser) block() (*BlockStmt, error) {
+	lbrace := p.peek()
+	stmts := make([]Stmt, 0)
+	p.step()
+	for !p.match(RBrace) && !p.end() {
+		if s := p.declaration(); s != nil {
+			stmts = append(stmts, s)
+		}
+	}
+	rbrace, err := p.consume(RBrace, "expected '}' after block")
+	if err != nil {
+		return nil, err
+	}
+	return &BlockStmt{LBrace: lbrace, Stmts: stmts, RBrace: rbrace}, nil
+}
+
+func (p *Parser) printStmt() (Stmt, error) {
+	kw := p.previous()
+	val, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	semi, err := p.consume(Semicolon, "expected ';' after value")
+	if err != nil {
+		return nil, err
+	}
+	return &PrintStmt{Keyword: kw, Expr: val, Semicolon: semi}, nil
+}
+
+func (p *Parser) exprStmt() (Stmt, error) {
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	semi, err := p.consume(Semicolon, "expected ';' after expression")
+	if err != nil {
+		return nil, err
+	}
+	return &ExprStmt{Expr: e, Semicolon: semi}, nil
+}
+
+func (p *Parser) assignment() (Expr, error) {
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.match(Equal) {
+		equals := p.peek()
+		p.step()
+		val, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		if exprVal, ok := expr.(*VarExpr); ok {
+			return &AssignExpr{Name: exprVal.Name, Value: val}, nil
+		}
+		if get, ok := expr.(*GetExpr); ok {
+			return &SetExpr{Object: get.Object, Name: get.Name, Val: val}, nil
+		}
+		if get, ok := expr.(*IndexGetExpr); ok {
+			return &IndexSetExpr{Object: get.Object, Bracket: get.Bracket, Index: get.Index, Val: val}, nil
+		}
+		return nil, p.error(equals, "invalid assignment target")
+	}
+	return expr, nil
+}
+
+func (p *Parser) or() (Expr, error) {
+	expr, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(Or) {
+		p.step()
+		op := p.previous()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		expr = &LogicalExpr{
+			Left:     expr,
+			Operator: op,
+			Right:    r,
+		}
+	}
+	return expr, nil
+}
+
+func (p *Parser) and() (Expr, error) {
+	expr, err := p.equality()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(And) {
+		op := p.peek()
+		p.step()
+		r, err := p.equality()
+		if err != nil {
+			return nil, err
+		}
+		expr = &LogicalExpr{
+			Left:     expr,
+			Operator: op,
+			Right:    r,
+		}
+	}
+	return expr, nil
+}
+
+func (p *Parser) expression() (Expr, error) {
+	return p.assignment()
+}
+
+func (p *Parser) equality() (Expr, error) {
+	e, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(BangEqual, EqualEqual) {
+		p.step()
+		op := p.previous()
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		e = &BinaryExpr{Left: e, Operator: op, Right: right}
+	}
+
+	return e, nil
+}
+
+func (p *Parser) match(tts ...TokenType) bool {
+	for _, t := range tts {
+		if p.currType(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) step() bool {
+	if p.end() {
+		return false
+	}
+	p.curr++
+	return true
+}
+
+// consume steps past the current token if it is of type tp, and returns
+// it. Otherwise it returns a ParseError with msg.
+func (p *Parser) consume(tp TokenType, msg string) (Token, error) {
+	if !p.match(tp) {
+		return Token{}, p.error(p.peek(), msg)
+	}
+	tok := p.peek()
+	p.step()
+	return tok, nil
+}
+
+func (p *Parser) error(tok Token, msg string) *ParseError {
+	return &ParseError{Token: tok, Msg: msg}
+}
+
+func (p *Parser) comparison() (Expr, error) {
+	e, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(Greater, GreaterEqual, Less, LessEqual) {
+		op := p.peek()
+		p.step()
+		r, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		e = &BinaryExpr{Left: e, Operator: op, Right: r}
+	}
+	return e, nil
+}
+
+func (p *Parser) term() (Expr, error) {
+	e, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(Minus, Plus) {
+		op := p.peek()
+		p.step()
+		r, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		e = &BinaryExpr{Left: e, Right: r, Operator: op}
+	}
+	return e, nil
+}
+
+func (p *Parser) factor() (Expr, error) {
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(Slash, Star) {
+		op := p.peek()
+		p.step()
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		e = &BinaryExpr{Left: e, Right: r, Operator: op}
+	}
+	return e, nil
+}
+
+func (p *Parser) unary() (Expr, error) {
+	if p.match(Bang, Minus) {
+		op := p.peek()
+		p.step()
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Operator: op, Right: r}, nil
+	}
+	return p.call()
+}
+
+func (p *Parser) call() (Expr, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.match(LParen) {
+			p.step()
+			expr, err = p.finishCall(expr)
+			if err != nil {
+				return nil, err
+			}
+		} else if p.match(Dot) {
+			p.step()
+			name, err := p.consume(Identifier, "expected property name after '.'")
+			if err != nil {
+				return nil, err
+			}
+			expr = &GetExpr{Object: expr, Name: name}
+		} else if p.match(LBracket) {
+			bracket := p.peek()
+			p.step()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			rbracket, err := p.consume(RBracket, "expected ']' after index")
+			if err != nil {
+				return nil, err
+			}
+			expr = &IndexGetExpr{Object: expr, Bracket: bracket, Index: index, RBracket: rbracket}
+		} else {
+			break
+		}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) finishCall(callee Expr) (Expr, error) {
+	args := make([]Expr, 0)
+	if !p.match(RParen) {
+		for {
+			if len(args) >= maxArgs {
+				return nil, p.error(p.peek(), fmt.Sprintf("can not have more than %d arguments", maxArgs))
+			}
+			expr, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, expr)
+			if !p.match(Comma) {
+				break
+			}
+			p.step()
+		}
+	}
+	paren, err := p.consume(RParen, "expected ')' after arguments")
+	if err != nil {
+		return nil, err
+	}
+	return &CallExpr{Callee: callee, Paren: paren, Args: args}, nil
+}
+
+func (p *Parser) primary() (Expr, error) {
+	switch {
+	case p.match(False):
+		p.step()
+		return &LiteralExpr{Token: p.previous(), Value: false}, nil
+	case p.match(True):
+		p.step()
+		return &LiteralExpr{Token: p.previous(), Value: true}, nil
+	case p.match(Nil):
+		p.step()
+		return &LiteralExpr{Token: p.previous(), Value: nil}, nil
+	case p.match(Number, String):
+		p.step()
+		return &LiteralExpr{Token: p.previous(), Value: p.previous().Literal}, nil
+	case p.match(This):
+		p.step()
+		return &ThisExpr{Keyword: p.previous()}, nil
+	case p.match(Super):
+		kw := p.peek()
+		p.step()
+		if _, err := p.consume(Dot, "expected '.' after 'super'"); err != nil {
+			return nil, err
+		}
+		method, err := p.consume(Identifier, "expected superclass method name")
+		if err != nil {
+			return nil, err
+		}
+		return &SuperExpr{Keyword: kw, Method: method}, nil
+	case p.match(Identifier):
+		p.step()
+		return &VarExpr{Name: p.previous()}, nil
+	case p.match(LParen):
+		lparen := p.peek()
+		p.step()
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		rparen, err := p.consume(RParen, "expected ')' after expression")
+		if err != nil {
+			return nil, err
+		}
+		return &GroupingExpr{LParen: lparen, Expr: e, RParen: rparen}, nil
+	case p.match(LBracket):
+		p.step()
+		return p.list()
+	case p.match(LBrace):
+		p.step()
+		return p.mapLiteral()
+	case p.match(Fun):
+		p.step()
+		return p.funExpr()
+	}
+	return nil, p.error(p.peek(), "expected expression")
+}
+
+// list parses the elements of a list literal, after the opening '['.
+func (p *Parser) list() (Expr, error) {
+	lbracket := p.previous()
+	elems := make([]Expr, 0)
+	if !p.match(RBracket) {
+		for {
+			e, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, e)
+			if !p.match(Comma) {
+				break
+			}
+			p.step()
+		}
+	}
+	rbracket, err := p.consume(RBracket, "expected ']' after list elements")
+	if err != nil {
+		return nil, err
+	}
+	return &ListExpr{LBracket: lbracket, Elems: elems, RBracket: rbracket}, nil
+}
+
+// mapLiteral parses the entries of a map literal, after the opening '{'.
+func (p *Parser) mapLiteral() (Expr, error) {
+	lbrace := p.previous()
+	keys := make([]Expr, 0)
+	vals := make([]Expr, 0)
+	if !p.match(RBrace) {
+		for {
+			k, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(Colon, "expected ':' after map key"); err != nil {
+				return nil, err
+			}
+			v, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+			vals = append(vals, v)
+			if !p.match(Comma) {
+				break
+			}
+			p.step()
+		}
+	}
+	rbrace, err := p.consume(RBrace, "expected '}' after map entries")
+	if err != nil {
+		return nil, err
+	}
+	return &MapExpr{LBrace: lbrace, Keys: keys, Vals: vals, RBrace: rbrace}, nil
+}
+
+// mapAhead reports whether the '{' at the current token opens a map
+// literal rather than a block. A statement can not start with a string or
+// number followed by ':', so '{' "key" ':' is always a map. Any other '{'
+// at the start of a statement, including '{}', is a block.
+func (p *Parser) mapAhead() bool {
+	if p.curr+2 >= len(p.tokens) {
+		return false
+	}
+	key, colon := p.tokens[p.curr+1], p.tokens[p.curr+2]
+	return (key.Type == String || key.Type == Number) && colon.Type == Colon
+}
+
+func (p *Parser) end() bool {
+	return p.peek().Type == EOF
+}
+
+// nextIs reports whether the token after the current one has type tp.
+func (p *Parser) nextIs(tp TokenType) bool {
+	return p.curr+1 < len(p.tokens) && p.tokens[p.curr+1].Type == tp
+}
+
+func (p *Parser) currType(tp TokenType) bool {
+	return p.peek().Type == tp
+}
+
+// peek returns the current token, the EOF token at the end of input.
+func (p *Parser) peek() Token {
+	return p.tokens[p.curr]
+}
+
+func (p *Parser) previous() Token {
+	return p.tokens[p.curr-1]
+}
@@ -0,0 +1,482 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Program is a script compiled to bytecode. It can be saved with WriteTo,
+// loaded again with ReadProgram and run with RunProgram, which skips
+// scanning, parsing and resolving the source.
+//
+// A saved program is laid out as follows, with integers big-endian and
+// strings as a u32 length followed by the bytes:
+//
+//	magic     "\x7fLOX"
+//	version   u16
+//	file      string
+//	script    function
+//
+// where a function is
+//
+//	name      string, empty for the script and anonymous functions
+//	arity     u8
+//	upvalues  u16
+//	constants u16 count, then a u8 tag and a value for each:
+//	          1 number (u64 IEEE 754 bits), 2 string, 3 function
+//	code      u32 length, then the bytecode
+//	lines     u32 count, then a u32 code offset, line and column for each
+//	          instruction that starts a new source position
+type Program struct {
+	script *funProto
+}
+
+// programMagic starts every saved program.
+const programMagic = "\x7fLOX"
+
+// programVersion changes whenever the layout or the instruction set does.
+const programVersion = 1
+
+const (
+	tagNumber   = 1
+	tagString   = 2
+	tagFunction = 3
+)
+
+// Compile compiles source to a Program, reporting the same errors as
+// RunFile before it runs anything.
+func (i *Interpreter) Compile(filename, source string) (*Program, error) {
+	stmts, err := i.parse(filename, source)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := compile(stmts, filename)
+	if err != nil {
+		return nil, &CompileError{err}
+	}
+	return &Program{script: proto}, nil
+}
+
+// RunProgram runs p on the bytecode VM, whatever the backend of i.
+func (i *Interpreter) RunProgram(ctx context.Context, p *Program) (Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, i.limitError(Token{File: p.script.file, Line: 1, Column: 1}, err)
+	}
+	return i.vm.exec(ctx, p.script)
+}
+
+// IsProgram reports whether data starts like a saved Program.
+func IsProgram(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(programMagic))
+}
+
+// WriteTo saves p to w.
+func (p *Program) WriteTo(w io.Writer) (int64, error) {
+	var e encoder
+	e.buf.WriteString(programMagic)
+	e.u16(programVersion)
+	e.str(p.script.file)
+	e.function(p.script)
+	n, err := w.Write(e.buf.Bytes())
+	return int64(n), err
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) u8(v int) {
+	e.buf.WriteByte(byte(v))
+}
+
+func (e *encoder) u16(v int) {
+	e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (e *encoder) u32(v int) {
+	e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (e *encoder) str(s string) {
+	e.u32(len(s))
+	e.buf.WriteString(s)
+}
+
+func (e *encoder) function(p *funProto) {
+	e.str(p.name)
+	e.u8(p.arity)
+	e.u16(p.upvalues)
+
+	e.u16(len(p.chunk.Constants))
+	for _, c := range p.chunk.Constants {
+		switch c := c.(type) {
+		case float64:
+			e.u8(tagNumber)
+			e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(c)))
+		case string:
+			e.u8(tagString)
+			e.str(c)
+		case *funProto:
+			e.u8(tagFunction)
+			e.function(c)
+		default:
+			// The compiler only makes the constants above.
+			panic(fmt.Sprintf("unexpected constant %T", c))
+		}
+	}
+
+	e.u32(len(p.chunk.Code))
+	e.buf.Write(p.chunk.Code)
+
+	e.u32(len(p.chunk.lines))
+	for _, l := range p.chunk.lines {
+		e.u32(l.offset)
+		e.u32(l.line)
+		e.u32(l.column)
+	}
+}
+
+// errTruncated is returned for a saved program that ends too early.
+var errTruncated = errors.New("unexpected end of data")
+
+// ReadProgram loads a Program saved by WriteTo. Malformed programs, for
+// example with jumps or constants out of range, are rejected.
+func ReadProgram(r io.Reader) (*Program, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !IsProgram(data) {
+		return nil, errors.New("read program: not a compiled Lox program")
+	}
+	d := decoder{data: data[len(programMagic):]}
+	if v := d.u16(); d.err == nil && v != programVersion {
+		return nil, fmt.Errorf("read program: unsupported version %d, want %d", v, programVersion)
+	}
+	file := d.str()
+	script := d.function(file)
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%d bytes of trailing data", len(d.data))
+	}
+	if d.err == nil && (script.arity != 0 || script.upvalues != 0) {
+		d.err = errors.New("script takes arguments or upvalues")
+	}
+	if d.err == nil {
+		d.err = script.verify()
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("read program: %w", d.err)
+	}
+	return &Program{script: script}, nil
+}
+
+// decoder reads a saved program. After the first error every read returns
+// zero values and err is kept.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.data) {
+		d.err = errTruncated
+		d.data = nil
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) u8() int {
+	b := d.take(1)
+	if b == nil {
+		return 0
+	}
+	return int(b[0])
+}
+
+func (d *decoder) u16() int {
+	b := d.take(2)
+	if b == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(b))
+}
+
+func (d *decoder) u32() int {
+	b := d.take(4)
+	if b == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) str() string {
+	return string(d.take(d.u32()))
+}
+
+func (d *decoder) function(file string) *funProto {
+	p := &funProto{file: file}
+	p.name = d.str()
+	p.arity = d.u8()
+	p.upvalues = d.u16()
+
+	n := d.u16()
+	for j := 0; j < n && d.err == nil; j++ {
+		switch tag := d.u8(); tag {
+		case tagNumber:
+			if b := d.take(8); b != nil {
+				p.chunk.Constants = append(p.chunk.Constants, math.Float64frombits(binary.BigEndian.Uint64(b)))
+			}
+		case tagString:
+			p.chunk.Constants = append(p.chunk.Constants, d.str())
+		case tagFunction:
+			p.chunk.Constants = append(p.chunk.Constants, d.function(file))
+		default:
+			if d.err == nil {
+				d.err = fmt.Errorf("unknown constant tag %d", tag)
+			}
+		}
+	}
+
+	p.chunk.Code = d.take(d.u32())
+
+	n = d.u32()
+	for j := 0; j < n && d.err == nil; j++ {
+		p.chunk.lines = append(p.chunk.lines, lineEntry{offset: d.u32(), line: d.u32(), column: d.u32()})
+	}
+	return p
+}
+
+// verify checks that the code of p, and of the functions it creates, can be
+// run without reading outside the code, the constants, the upvalues or the
+// stack slots of its frame, without popping more than it pushed, and
+// without popping a captured slot before its upvalue is closed.
+func (p *funProto) verify() error {
+	code, consts := p.chunk.Code, p.chunk.Constants
+	errorf := func(offset int, format string, args ...any) error {
+		return fmt.Errorf("%s: offset %d: %s", p, offset, fmt.Sprintf(format, args...))
+	}
+
+	// Decode the instructions and check their operands. next holds the
+	// offset of the following instruction for every offset that starts
+	// one, and zero elsewhere.
+	next := make([]int, len(code))
+	for off := 0; off < len(code); off = next[off] {
+		op := Opcode(code[off])
+		if int(op) >= len(opcodes) {
+			return errorf(off, "unknown opcode %d", byte(op))
+		}
+		kind := opcodes[op].operand
+		next[off] = off + 1 + kind.width()
+		if next[off] > len(code) {
+			return errorf(off, "truncated %v", op)
+		}
+		switch kind {
+		case constOperand, nameOperand, closureOperand:
+			j := p.chunk.readShort(off + 1)
+			if j >= len(consts) {
+				return errorf(off, "constant %d out of range", j)
+			}
+			var ok bool
+			switch kind {
+			case constOperand:
+				switch consts[j].(type) {
+				case float64, string:
+					ok = true
+				}
+			case nameOperand:
+				_, ok = consts[j].(string)
+			case closureOperand:
+				var fn *funProto
+				if fn, ok = consts[j].(*funProto); ok {
+					next[off] += 2 * fn.upvalues
+					if next[off] > len(code) {
+						return errorf(off, "truncated %v", op)
+					}
+					for k := off + 3; k < next[off]; k += 2 {
+						if code[k] > 1 || code[k] == 0 && int(code[k+1]) >= p.upvalues {
+							return errorf(off, "bad upvalue capture")
+						}
+					}
+				}
+			}
+			if !ok {
+				return errorf(off, "constant %d has the wrong type for %v", j, op)
+			}
+		case upvalueOperand:
+			if int(code[off+1]) >= p.upvalues {
+				return errorf(off, "upvalue %d out of range", code[off+1])
+			}
+		}
+	}
+
+	// Follow every path through the code, tracking the depth of the
+	// frame's stack, which must be the same however an instruction is
+	// reached, and the slots open upvalues may refer to on any path there.
+	// The frame starts with the callee and the arguments.
+	states := make([]frameState, len(code))
+	for off := range states {
+		states[off].depth = -1
+	}
+	var work []int
+	flow := func(from, to int, st frameState) error {
+		if to < 0 || to >= len(code) || next[to] == 0 {
+			return errorf(from, "%v to offset %d, which starts no instruction", Opcode(code[from]), to)
+		}
+		switch prev := states[to]; {
+		case prev.depth == -1:
+			states[to] = st
+			work = append(work, to)
+		case prev.depth != st.depth:
+			return errorf(to, "stack depth is %d or %d depending on the path", prev.depth, st.depth)
+		case prev.captured.union(st.captured) != prev.captured:
+			states[to].captured = prev.captured.union(st.captured)
+			work = append(work, to)
+		}
+		return nil
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("%s: code is empty", p)
+	}
+	if err := flow(0, 0, frameState{depth: p.arity + 1}); err != nil {
+		return err
+	}
+	for len(work) > 0 {
+		off := work[len(work)-1]
+		work = work[:len(work)-1]
+		op, st := Opcode(code[off]), states[off]
+		d := st.depth
+
+		pops, pushes := p.chunk.stackEffect(off)
+		if d < pops {
+			return errorf(off, "%v pops %d values from a stack of %d", op, pops, d)
+		}
+		switch op {
+		case OpCloseUpvalue:
+			st.captured.remove(d - 1)
+		default:
+			for slot := d - pops; slot < d; slot++ {
+				if st.captured.has(slot) {
+					return errorf(off, "%v pops captured stack slot %d without closing it", op, slot)
+				}
+			}
+		}
+		switch opcodes[op].operand {
+		case slotOperand:
+			if int(code[off+1]) >= d {
+				return errorf(off, "stack slot %d out of range", code[off+1])
+			}
+		case closureOperand:
+			// A local function captures itself in the slot it is
+			// about to be pushed to.
+			for k := off + 3; k < next[off]; k += 2 {
+				if code[k] == 1 && int(code[k+1]) > d {
+					return errorf(off, "captured stack slot %d out of range", code[k+1])
+				}
+				if code[k] == 1 {
+					st.captured.add(int(code[k+1]))
+				}
+			}
+		}
+		st.depth += pushes - pops
+
+		var err error
+		switch op {
+		case OpReturn:
+			continue
+		case OpJump:
+			err = flow(off, next[off]+p.chunk.readShort(off+1), st)
+		case OpLoop:
+			err = flow(off, next[off]-p.chunk.readShort(off+1), st)
+		case OpJumpIfFalse:
+			err = flow(off, next[off]+p.chunk.readShort(off+1), st)
+			if err == nil {
+				err = flow(off, next[off], st)
+			}
+		default:
+			if next[off] == len(code) {
+				return errorf(off, "code ends without %v", OpReturn)
+			}
+			err = flow(off, next[off], st)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, c := range consts {
+		if fn, ok := c.(*funProto); ok {
+			if err := fn.verify(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// frameState is what verify knows about a frame before an instruction.
+type frameState struct {
+	depth int
+	// captured holds the stack slots that open upvalues may refer to.
+	captured slotSet
+}
+
+// slotSet is a set of the stack slots an instruction operand can name.
+type slotSet [4]uint64
+
+func (s *slotSet) add(slot int) {
+	s[slot/64] |= 1 << (slot % 64)
+}
+
+func (s *slotSet) remove(slot int) {
+	if slot < 256 {
+		s[slot/64] &^= 1 << (slot % 64)
+	}
+}
+
+func (s *slotSet) has(slot int) bool {
+	return slot < 256 && s[slot/64]&(1<<(slot%64)) != 0
+}
+
+func (s slotSet) union(t slotSet) slotSet {
+	for j := range s {
+		s[j] |= t[j]
+	}
+	return s
+}
+
+// stackEffect returns how many values the instruction at offset pops off
+// the stack, and how many it then pushes. Values it only peeks at count as
+// popped and pushed again.
+func (c *Chunk) stackEffect(offset int) (pops, pushes int) {
+	switch Opcode(c.Code[offset]) {
+	case OpConstant, OpNil, OpTrue, OpFalse, OpGetLocal, OpGetGlobal, OpGetUpvalue, OpClosure, OpClass:
+		return 0, 1
+	case OpPop, OpDefineGlobal, OpPrint, OpCloseUpvalue, OpReturn:
+		return 1, 0
+	case OpSetLocal, OpSetGlobal, OpSetUpvalue, OpGetProperty, OpNot, OpNegate, OpJumpIfFalse:
+		return 1, 1
+	case OpSetProperty, OpGetSuper, OpEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual,
+		OpAdd, OpSubtract, OpMultiply, OpDivide, OpInherit, OpMethod, OpIndexGet:
+		return 2, 1
+	case OpIndexSet:
+		return 3, 1
+	case OpCall:
+		return int(c.Code[offset+1]) + 1, 1
+	case OpList:
+		return c.readShort(offset + 1), 1
+	case OpMap:
+		return 2 * c.readShort(offset+1), 1
+	}
+	return 0, 0
+}
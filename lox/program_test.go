@@ -0,0 +1,267 @@
+package lox_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"glox/lox"
+)
+
+// savedFn is a function record of a saved program, written out by hand so
+// tests can make programs the compiler never would.
+type savedFn struct {
+	name     string
+	arity    int
+	upvalues int
+	consts   []any // float64, string or savedFn
+	code     []byte
+}
+
+func (f savedFn) encode(b *bytes.Buffer) {
+	str := func(s string) {
+		b.Write(binary.BigEndian.AppendUint32(nil, uint32(len(s))))
+		b.WriteString(s)
+	}
+	str(f.name)
+	b.WriteByte(byte(f.arity))
+	b.Write(binary.BigEndian.AppendUint16(nil, uint16(f.upvalues)))
+	b.Write(binary.BigEndian.AppendUint16(nil, uint16(len(f.consts))))
+	for _, c := range f.consts {
+		switch c := c.(type) {
+		case float64:
+			b.WriteByte(1)
+			b.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(c)))
+		case string:
+			b.WriteByte(2)
+			str(c)
+		case savedFn:
+			b.WriteByte(3)
+			c.encode(b)
+		}
+	}
+	b.Write(binary.BigEndian.AppendUint32(nil, uint32(len(f.code))))
+	b.Write(f.code)
+	b.Write(binary.BigEndian.AppendUint32(nil, 0))
+}
+
+// saved returns a saved program whose script is f.
+func saved(f savedFn) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x7fLOX")
+	b.Write([]byte{0, 1})
+	b.Write([]byte{0, 0, 0, 0})
+	f.encode(&b)
+	return b.Bytes()
+}
+
+// code assembles opcodes and operand bytes.
+func code(parts ...any) []byte {
+	var b []byte
+	for _, p := range parts {
+		switch p := p.(type) {
+		case lox.Opcode:
+			b = append(b, byte(p))
+		case int:
+			b = append(b, byte(p))
+		}
+	}
+	return b
+}
+
+func TestProgramRoundTrip(t *testing.T) {
+	const src = `
+fun counter() {
+  var n = 0;
+  fun incr() { n = n + 1; return n; }
+  return incr;
+}
+var c = counter();
+c();
+print c();
+class A { init(x) { this.x = x; } }
+class B < A { get() { return this.x; } }
+print B("ok").get();
+`
+	prog, err := lox.New().Compile("round.lox", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	if _, err := prog.WriteTo(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if !lox.IsProgram(saved.Bytes()) {
+		t.Fatal("IsProgram of a saved program is false")
+	}
+	loaded, err := lox.ReadProgram(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := lox.New(lox.WithStdout(&out)).RunProgram(context.Background(), loaded); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "2\nok\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRunProgramTreeWalkerClass(t *testing.T) {
+	// RunProgram runs on the VM even when the interpreter is a tree-walker,
+	// so compiled code meets classes and methods the tree-walker declared.
+	var out bytes.Buffer
+	i := lox.New(lox.WithBackend(lox.TreeWalker), lox.WithStdout(&out))
+	_, err := i.RunFile(context.Background(), "a.lox", `
+class A {
+  init(x) { this.x = x; }
+  get() { return this.x; }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := i.Compile("b.lox", `
+var a = A(5);
+print a.x;
+print a.get();
+class B < A {
+  init(x) { super.init(x * 2); }
+}
+print B(5).get();
+class C < A {}
+print C(7).x;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.RunProgram(context.Background(), prog); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "5\n5\n10\n7\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestReadProgramRejects(t *testing.T) {
+	valid := savedFn{consts: []any{"x"}, code: code(lox.OpNil, lox.OpReturn)}
+	withCode := func(parts ...any) []byte {
+		f := valid
+		f.code = code(parts...)
+		return saved(f)
+	}
+	inner := savedFn{name: "f", upvalues: 1, code: code(lox.OpNil, lox.OpReturn)}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not a program", []byte("print 1;"), "not a compiled Lox program"},
+		{"version", append([]byte("\x7fLOX\x00\x02"), saved(valid)[6:]...), "unsupported version 2"},
+		{"truncated", saved(valid)[:20], "unexpected end of data"},
+		{"trailing data", append(saved(valid), 0), "1 bytes of trailing data"},
+		{"script arity", saved(savedFn{arity: 1, code: code(lox.OpNil, lox.OpReturn)}), "script takes arguments"},
+		{"empty code", withCode(), "code is empty"},
+		{"unknown opcode", withCode(200), "unknown opcode 200"},
+		{"truncated operand", withCode(lox.OpNil, lox.OpConstant, 0), "truncated OP_CONSTANT"},
+		{"constant range", withCode(lox.OpConstant, 0, 9, lox.OpReturn), "constant 9 out of range"},
+		{"constant type", withCode(lox.OpClosure, 0, 0, lox.OpReturn), "wrong type for OP_CLOSURE"},
+		{"no return", withCode(lox.OpNil), "code ends without OP_RETURN"},
+		{"pop empty stack", withCode(lox.OpPop, lox.OpPop, lox.OpPop, lox.OpNil, lox.OpReturn), "OP_POP pops 1 values from a stack of 0"},
+		{"call underflow", withCode(lox.OpCall, 3, lox.OpReturn), "OP_CALL pops 4 values from a stack of 1"},
+		{"local slot", withCode(lox.OpGetLocal, 200, lox.OpReturn), "stack slot 200 out of range"},
+		{"upvalue", withCode(lox.OpGetUpvalue, 0, lox.OpReturn), "upvalue 0 out of range"},
+		{"jump range", withCode(lox.OpJump, 0, 9, lox.OpNil, lox.OpReturn), "starts no instruction"},
+		{"jump into operand", withCode(lox.OpJump, 0, 1, lox.OpConstant, 0, 0, lox.OpReturn), "starts no instruction"},
+		{"loop range", withCode(lox.OpNil, lox.OpLoop, 0, 9, lox.OpReturn), "starts no instruction"},
+		{
+			"stack depth",
+			withCode(lox.OpTrue, lox.OpJumpIfFalse, 0, 1, lox.OpNil, lox.OpReturn),
+			"stack depth is 2 or 3",
+		},
+		{
+			"captured local",
+			saved(savedFn{consts: []any{inner}, code: code(lox.OpClosure, 0, 0, 1, 5, lox.OpReturn)}),
+			"captured stack slot 5 out of range",
+		},
+		{
+			"bad capture",
+			saved(savedFn{consts: []any{inner}, code: code(lox.OpClosure, 0, 0, 2, 0, lox.OpReturn)}),
+			"bad upvalue capture",
+		},
+		{
+			// The closure captures slot 2, which is popped without
+			// closing its upvalue before the closure reads it.
+			"dangling upvalue",
+			saved(savedFn{
+				consts: []any{savedFn{name: "f", upvalues: 1, code: code(lox.OpGetUpvalue, 0, lox.OpReturn)}, "f"},
+				code: code(
+					lox.OpNil, lox.OpNil,
+					lox.OpClosure, 0, 0, 1, 2,
+					lox.OpDefineGlobal, 0, 1,
+					lox.OpPop, lox.OpPop,
+					lox.OpGetGlobal, 0, 1,
+					lox.OpCall, 0,
+					lox.OpReturn,
+				),
+			}),
+			"OP_POP pops captured stack slot 2 without closing it",
+		},
+		{
+			"returned self capture",
+			saved(savedFn{consts: []any{inner}, code: code(lox.OpClosure, 0, 0, 1, 1, lox.OpReturn)}),
+			"OP_RETURN pops captured stack slot 1 without closing it",
+		},
+		{
+			"nested function",
+			saved(savedFn{consts: []any{savedFn{name: "g", code: code(lox.OpPop, lox.OpPop, lox.OpReturn)}}, code: code(lox.OpClosure, 0, 0, lox.OpReturn)}),
+			"<fn g>: offset 1: OP_POP pops 1 values from a stack of 0",
+		},
+	}
+	if _, err := lox.ReadProgram(bytes.NewReader(saved(valid))); err != nil {
+		t.Fatalf("valid program: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lox.ReadProgram(bytes.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProgramCorrupted(t *testing.T) {
+	// Whatever byte of a saved program is damaged, loading and running it
+	// must fail or succeed without panicking.
+	prog, err := lox.New().Compile("t.lox", `
+class A { init(x) { this.x = x; } }
+class B < A { get() { return super.get; } }
+fun f(n) { var m = n; return fun () { return m + n; }; }
+var l = [1, "a", {1: 2}];
+print f(1)() + l[0];
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	prog.WriteTo(&buf)
+	data := buf.Bytes()
+	for j := 4; j < len(data); j++ {
+		for _, b := range []byte{0, 1, 5, 0xff, data[j] + 1, data[j] - 1} {
+			damaged := append([]byte(nil), data...)
+			damaged[j] = b
+			p, err := lox.ReadProgram(bytes.NewReader(damaged))
+			if err != nil {
+				continue
+			}
+			var out bytes.Buffer
+			i := lox.New(lox.WithMaxSteps(10000), lox.WithStdout(&out))
+			i.RunProgram(context.Background(), p)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+type Resolver struct {
+	// behaves like a stack
+	Scopes *Scopes
+
+	currentFun   funType
+	currentClass classType
+
+	// loops counts the loops enclosing the current statement within the
+	// current function.
+	loops int
+
+	// seq numbers declarations so diagnostics come out in source order.
+	seq  int
+	errs []error
+}
+
+// funType tells what kind of function body the resolver is in.
+type funType int
+
+const (
+	funTypeNone funType = iota
+	funTypeFunction
+	funTypeMethod
+	funTypeInitializer
+)
+
+// classType tells what kind of class body the resolver is in.
+type classType int
+
+const (
+	classTypeNone classType = iota
+	classTypeClass
+	classTypeSubclass
+)
+
+// binding is what the resolver knows about a local variable.
+type binding struct {
+	name    Token
+	seq     int
+	defined bool
+	used    bool
+}
+
+type Scopes []map[string]*binding
+
+func (s *Scopes) pop() {
+	*s = (*s)[:len(*s)-1]
+}
+
+func (s *Scopes) push(scope map[string]*binding) {
+	*s = append(*s, scope)
+}
+
+func (s *Scopes) alterTop(name string, b *binding) {
+	topScope := (*s)[len(*s)-1]
+	topScope[name] = b
+}
+
+func (s *Scopes) peek() map[string]*binding {
+	return (*s)[len(*s)-1]
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{Scopes: &Scopes{}}
+}
+
+// Resolve records the scope depth of every local variable reference in
+// stmts on the expressions themselves. All problems found are returned
+// together.
+func (r *Resolver) Resolve(stmts []Stmt) error {
+	r.resolve(stmts)
+	return errors.Join(r.errs...)
+}
+
+func (r *Resolver) resolve(val any) {
+	var err error
+	switch v := val.(type) {
+	case Stmt:
+		_, err = v.Accept(r)
+	case Expr:
+		_, err = v.Accept(r)
+	case []Stmt:
+		for _, s := range v {
+			r.resolve(s)
+		}
+	}
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+}
+
+func (r *Resolver) error(tok Token, msg string) error {
+	return fmt.Errorf("[%s] resolve error at '%s': %s", tok.Pos(), tok.Lexeme, msg)
+}
+
+// resolveLocal records in res how many scopes away from the innermost one
+// name is bound. Globals are left unresolved.
+func (r *Resolver) resolveLocal(res *resolution, name string) {
+	for j := len(*r.Scopes) - 1; j >= 0; j-- {
+		if b, ok := (*r.Scopes)[j][name]; ok {
+			b.used = true
+			*res = resolution{local: true, depth: len(*r.Scopes) - 1 - j}
+			return
+		}
+	}
+	*res = resolution{}
+}
+
+func (r *Resolver) resolveFun(params []Token, body []Stmt, kind funType) {
+	enclosing, loops := r.currentFun, r.loops
+	r.currentFun, r.loops = kind, 0
+	defer func() { r.currentFun, r.loops = enclosing, loops }()
+
+	r.startScope()
+	for _, p := range params {
+		r.declare(p)
+		r.define(p)
+		r.Scopes.peek()[p.Lexeme].used = true
+	}
+	r.resolve(body)
+	r.endScope()
+}
+
+func (r *Resolver) startScope() {
+	s := make(map[string]*binding)
+	r.Scopes.push(s)
+}
+
+// endScope pops the innermost scope, reporting variables declared in it
+// that were never read.
+func (r *Resolver) endScope() {
+	unused := make([]*binding, 0)
+	for _, b := range r.Scopes.peek() {
+		if !b.used {
+			unused = append(unused, b)
+		}
+	}
+	sort.Slice(unused, func(j, k int) bool { return unused[j].seq < unused[k].seq })
+	for _, b := range unused {
+		r.errs = append(r.errs, r.error(b.name, "local variable is never used"))
+	}
+	r.Scopes.pop()
+}
+
+// implicit defines a variable the user never declares, like "this".
+func (r *Resolver) implicit(name string) {
+	r.Scopes.alterTop(name, &binding{defined: true, used: true})
+}
+
+func (r *Resolver) declare(name Token) {
+	if len(*r.Scopes) == 0 {
+		return
+	}
+	if _, ok := r.Scopes.peek()[name.Lexeme]; ok {
+		r.errs = append(r.errs, r.error(name, "already a variable with this name in this scope"))
+	}
+	r.seq++
+	r.Scopes.alterTop(name.Lexeme, &binding{name: name, seq: r.seq})
+}
+
+func (r *Resolver) define(name Token) {
+	if len(*r.Scopes) == 0 {
+		return
+	}
+	r.Scopes.peek()[name.Lexeme].defined = true
+}
+
+func (r *Resolver) visitBlockStmt(stmt *BlockStmt) (Value, error) {
+	r.startScope()
+	r.resolve(stmt.Stmts)
+	r.endScope()
+	return nil, nil
+}
+
+func (r *Resolver) visitVarStmt(stmt *VarStmt) error {
+	r.declare(stmt.Name)
+	if stmt.Init != nil {
+		r.resolve(stmt.Init)
+	}
+	r.define(stmt.Name)
+	return nil
+}
+
+func (r *Resolver) visitVarExpr(expr *VarExpr) (Value, error) {
+	if len(*r.Scopes) > 0 {
+		if b, ok := r.Scopes.peek()[expr.Name.Lexeme]; ok && !b.defined {
+			return nil, r.error(expr.Name, "can not read local variable in its own initializer")
+		}
+	}
+	r.resolveLocal(&expr.res, expr.Name.Lexeme)
+	return nil, nil
+}
+
+func (r *Resolver) visitWhileStmt(stmt *WhileStmt) (Value, error) {
+	r.resolve(stmt.Cond)
+	r.loops++
+	r.resolve(stmt.Body)
+	r.loops--
+	if stmt.Incr != nil {
+		r.resolve(stmt.Incr)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitBreakStmt(stmt *BreakStmt) (Value, error) {
+	if r.loops == 0 {
+		return nil, r.error(stmt.Keyword, "can not break outside of a loop")
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitContinueStmt(stmt *ContinueStmt) (Value, error) {
+	if r.loops == 0 {
+		return nil, r.error(stmt.Keyword, "can not continue outside of a loop")
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitAssignExpr(expr *AssignExpr) (Value, error) {
+	r.resolve(expr.Value)
+	r.resolveLocal(&expr.res, expr.Name.Lexeme)
+	return nil, nil
+}
+func (r *Resolver) visitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	r.resolve(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) visitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	r.resolve(expr.Left)
+	r.resolve(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) visitCallExpr(expr *CallExpr) (Value, error) {
+	r.resolve(expr.Callee)
+	for _, arg := range expr.Args {
+		r.resolve(arg)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitGetExpr(expr *GetExpr) (Value, error) {
+	r.resolve(expr.Object)
+	return nil, nil
+}
+
+func (r *Resolver) visitSetExpr(expr *SetExpr) (Value, error) {
+	r.resolve(expr.Val)
+	r.resolve(expr.Object)
+	return nil, nil
+}
+
+func (r *Resolver) visitSuperExpr(expr *SuperExpr) (Value, error) {
+	switch r.currentClass {
+	case classTypeNone:
+		return nil, r.error(expr.Keyword, "can not use 'super' outside of a class")
+	case classTypeClass:
+		return nil, r.error(expr.Keyword, "can not use 'super' in a class with no superclass")
+	}
+	r.resolveLocal(&expr.res, expr.Keyword.Lexeme)
+	return nil, nil
+}
+
+func (r *Resolver) visitThisExpr(expr *ThisExpr) (Value, error) {
+	if r.currentClass == classTypeNone {
+		return nil, r.error(expr.Keyword, "can not use 'this' outside of a class")
+	}
+	r.resolveLocal(&expr.res, expr.Keyword.Lexeme)
+	return nil, nil
+}
+
+func (r *Resolver) visitClassStmt(stmt *ClassStmt) (Value, error) {
+	enclosing := r.currentClass
+	r.currentClass = classTypeClass
+	defer func() { r.currentClass = enclosing }()
+
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	if stmt.Superclass != nil {
+		if stmt.Superclass.Name.Lexeme == stmt.Name.Lexeme {
+			return nil, r.error(stmt.Name, "a class can not inherit from itself")
+		}
+		r.currentClass = classTypeSubclass
+		r.resolve(stmt.Superclass)
+		r.startScope()
+		defer r.endScope()
+		r.implicit("super")
+	}
+
+	r.startScope()
+	r.implicit("this")
+	for _, m := range stmt.Methods {
+		kind := funTypeMethod
+		if m.Name.Lexeme == "init" {
+			kind = funTypeInitializer
+		}
+		r.resolveFun(m.Params, m.Body, kind)
+	}
+	r.endScope()
+	return nil, nil
+}
+
+func (r *Resolver) visitExprStmt(stmt *ExprStmt) (Value, error) {
+	r.resolve(stmt.Expr)
+	return nil, nil
+}
+
+func (r *Resolver) visitFunStmt(stmt *FunStmt) (Value, error) {
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.resolveFun(stmt.Params, stmt.Body, funTypeFunction)
+	return nil, nil
+}
+
+func (r *Resolver) visitIfStmt(stmt *IfStmt) (Value, error) {
+	r.resolve(stmt.Cond)
+	r.resolve(stmt.Then)
+	if stmt.Else != nil {
+		r.resolve(stmt.Else)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitPrintStmt(stmt *PrintStmt) (Value, error) {
+	r.resolve(stmt.Expr)
+	return nil, nil
+}
+
+func (r *Resolver) visitRetStmt(stmt *RetStmt) (Value, error) {
+	if r.currentFun == funTypeNone {
+		return nil, r.error(stmt.Keyword, "can not return from top-level code")
+	}
+	if stmt.Val != nil {
+		if r.currentFun == funTypeInitializer {
+			return nil, r.error(stmt.Keyword, "can not return a value from an initializer")
+		}
+		r.resolve(stmt.Val)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	r.resolveFun(expr.Params, expr.Body, funTypeFunction)
+	return nil, nil
+}
+
+func (r *Resolver) visitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	return nil, nil
+}
+
+func (r *Resolver) visitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	r.resolve(expr.Left)
+	r.resolve(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) visitIndexGetExpr(expr *IndexGetExpr) (Value, error) {
+	r.resolve(expr.Object)
+	r.resolve(expr.Index)
+	return nil, nil
+}
+
+func (r *Resolver) visitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	r.resolve(expr.Object)
+	r.resolve(expr.Index)
+	r.resolve(expr.Val)
+	return nil, nil
+}
+
+func (r *Resolver) visitListExpr(expr *ListExpr) (Value, error) {
+	for _, e := range expr.Elems {
+		r.resolve(e)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitMapExpr(expr *MapExpr) (Value, error) {
+	for j := range expr.Keys {
+		r.resolve(expr.Keys[j])
+		r.resolve(expr.Vals[j])
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	r.resolve(expr.Expr)
+	return nil, nil
+}
@@ -0,0 +1,21 @@
+package lox
+
+import "errors"
+
+// FunRet carries the value of a return statement up through the
+// statements enclosing it until it reaches the function being called.
+// It travels as an error so every visitor passes it along untouched.
+type FunRet struct {
+	Val Value
+}
+
+func (r *FunRet) Error() string {
+	return "return outside of function"
+}
+
+// errBreak and errContinue unwind from a break or continue statement to
+// the innermost enclosing loop, the same way FunRet does for return.
+var (
+	errBreak    = errors.New("break outside of loop")
+	errContinue = errors.New("continue outside of loop")
+)
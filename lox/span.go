@@ -0,0 +1,80 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a position in source code. Line and Column count from 1, Column
+// in bytes. Offset is the byte offset from the start of the source.
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+// IsValid reports whether the position is set. Nodes the parser makes up,
+// like the implicit "true" of an empty for-loop condition, have no
+// position.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	if p.File != "" {
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+	return fmt.Sprintf("line %d:%d", p.Line, p.Column)
+}
+
+// Span is the range of source from Start up to, but not including, End.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+func (s Span) IsValid() bool {
+	return s.Start.IsValid()
+}
+
+func (s Span) String() string {
+	if s.Start.Line == s.End.Line {
+		return fmt.Sprintf("%s-%d", s.Start, s.End.Column)
+	}
+	return fmt.Sprintf("%s-%d:%d", s.Start, s.End.Line, s.End.Column)
+}
+
+// spanOf returns the span running from the start of first to the end of
+// last. Either may be invalid, in which case the other is used.
+func spanOf(first, last Span) Span {
+	if !first.IsValid() {
+		return last
+	}
+	if !last.IsValid() {
+		return first
+	}
+	return Span{Start: first.Start, End: last.End}
+}
+
+// Pos returns where the token starts.
+func (t Token) Pos() Pos {
+	return Pos{File: t.File, Line: t.Line, Column: t.Column, Offset: t.Offset}
+}
+
+// Span returns the range of source the token's lexeme covers.
+func (t Token) Span() Span {
+	start := t.Pos()
+	if !start.IsValid() {
+		return Span{}
+	}
+	end := start
+	end.Offset += len(t.Lexeme)
+	if nl := strings.LastIndexByte(t.Lexeme, '\n'); nl >= 0 {
+		end.Line += strings.Count(t.Lexeme, "\n")
+		end.Column = len(t.Lexeme) - nl
+	} else {
+		end.Column += len(t.Lexeme)
+	}
+	return Span{Start: start, End: end}
+}
@@ -0,0 +1,37 @@
+package lox
+
+import (
+	"bufio"
+	"io"
+)
+
+// WithStdout sets where print writes. It defaults to os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stdout = w
+	}
+}
+
+// WithStderr sets where the eprint native writes. It defaults to
+// os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stderr = w
+	}
+}
+
+// WithStdin sets where the readLine native reads from. It defaults to
+// os.Stdin. A *bufio.Reader is used as is, so the host can keep reading
+// from it between runs without losing buffered input.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) {
+		i.stdin = bufioReader(r)
+	}
+}
+
+func bufioReader(r io.Reader) *bufio.Reader {
+	if br, ok := r.(*bufio.Reader); ok {
+		return br
+	}
+	return bufio.NewReader(r)
+}
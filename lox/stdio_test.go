@@ -0,0 +1,76 @@
+package lox_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"glox/lox"
+)
+
+func TestReadLine(t *testing.T) {
+	for _, backend := range backends {
+		in := strings.NewReader("one\r\ntwo\n\nlast")
+		out, err := runSource(backend, `
+var line = readLine();
+while (line != nil) {
+  print "<" + line + ">";
+  line = readLine();
+}
+print readLine();
+`, lox.WithStdin(in))
+		if err != nil {
+			t.Fatalf("%v: %v", backend, err)
+		}
+		if want := "<one>\n<two>\n<>\n<last>\nnil\n"; out != want {
+			t.Errorf("%v: output = %q, want %q", backend, out, want)
+		}
+	}
+}
+
+func TestStdinSharedAcrossRuns(t *testing.T) {
+	// A *bufio.Reader is used as is, so the host reads on where the
+	// script stopped.
+	in := bufio.NewReader(strings.NewReader("a\nb\nc\n"))
+	var out bytes.Buffer
+	i := lox.New(lox.WithStdin(in), lox.WithStdout(&out))
+	if _, err := i.Run(context.Background(), `print readLine();`); err != nil {
+		t.Fatal(err)
+	}
+	if line, _ := in.ReadString('\n'); line != "b\n" {
+		t.Errorf("host read %q, want %q", line, "b\n")
+	}
+	if _, err := i.Run(context.Background(), `print readLine();`); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\nc\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestStderr(t *testing.T) {
+	for _, backend := range backends {
+		var stderr bytes.Buffer
+		out, err := runSource(backend, `eprint("oops"); print "done";`, lox.WithStderr(&stderr))
+		if err != nil {
+			t.Fatalf("%v: %v", backend, err)
+		}
+		if out != "done\n" || stderr.String() != "oops\n" {
+			t.Errorf("%v: stdout %q, stderr %q; want %q, %q", backend, out, stderr.String(), "done\n", "oops\n")
+		}
+	}
+}
+
+func TestWithArgs(t *testing.T) {
+	for _, backend := range backends {
+		out, err := runSource(backend, `print args; print len(args);`, lox.WithArgs([]string{"a", "b c"}))
+		if err != nil {
+			t.Fatalf("%v: %v", backend, err)
+		}
+		if want := "[\"a\", \"b c\"]\n2\n"; out != want {
+			t.Errorf("%v: output = %q, want %q", backend, out, want)
+		}
+	}
+}
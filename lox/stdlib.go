@@ -0,0 +1,296 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// defineStdlib registers the built-in native functions. Strings are
+// indexed by character, not by byte.
+func (i *Interpreter) defineStdlib() {
+	// Time.
+	i.DefineNative("clock", 0, func(_ *Interpreter, _ []Value) (Value, error) {
+		return float64(time.Now().UnixNano()) / float64(time.Second), nil
+	})
+
+	// I/O.
+	i.DefineNative("readLine", 0, func(interp *Interpreter, _ []Value) (Value, error) {
+		line, err := interp.stdin.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			return nil, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("readLine: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		return strings.TrimSuffix(line, "\r"), nil
+	})
+	i.DefineNative("eprint", 1, func(interp *Interpreter, args []Value) (Value, error) {
+		if _, err := fmt.Fprintln(interp.stderr, Stringify(args[0])); err != nil {
+			return nil, fmt.Errorf("eprint: %v", err)
+		}
+		return nil, nil
+	})
+
+	// Strings.
+	i.DefineNative("len", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case *List:
+			return float64(len(v.Elems)), nil
+		case *Map:
+			return float64(v.Len()), nil
+		}
+		return nil, argError("len", 0, "a string, list or map", args[0])
+	})
+	i.DefineNative("substr", 3, func(_ *Interpreter, args []Value) (Value, error) {
+		s, err := stringArg("substr", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		start, err := intArg("substr", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		end, err := intArg("substr", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(s)
+		if start < 0 || end > len(runes) || start > end {
+			return nil, fmt.Errorf("substr: range [%d, %d) out of bounds for length %d", start, end, len(runes))
+		}
+		return string(runes[start:end]), nil
+	})
+	i.DefineNative("upper", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		s, err := stringArg("upper", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToUpper(s), nil
+	})
+	i.DefineNative("lower", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		s, err := stringArg("lower", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToLower(s), nil
+	})
+	i.DefineNative("index", 2, func(_ *Interpreter, args []Value) (Value, error) {
+		s, err := stringArg("index", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := stringArg("index", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		j := strings.Index(s, sub)
+		if j < 0 {
+			return float64(-1), nil
+		}
+		return float64(utf8.RuneCountInString(s[:j])), nil
+	})
+	i.DefineNative("split", 2, func(_ *Interpreter, args []Value) (Value, error) {
+		s, err := stringArg("split", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := stringArg("split", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(s, sep)
+		elems := make([]Value, len(parts))
+		for j, p := range parts {
+			elems[j] = p
+		}
+		return NewList(elems), nil
+	})
+	i.DefineNative("join", 2, func(_ *Interpreter, args []Value) (Value, error) {
+		l, err := listArg("join", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := stringArg("join", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(l.Elems))
+		for j, e := range l.Elems {
+			parts[j] = Stringify(e)
+		}
+		return strings.Join(parts, sep), nil
+	})
+
+	// Lists.
+	i.DefineNative("push", 2, func(_ *Interpreter, args []Value) (Value, error) {
+		l, err := listArg("push", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		l.Elems = append(l.Elems, args[1])
+		return nil, nil
+	})
+	i.DefineNative("pop", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		l, err := listArg("pop", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(l.Elems) == 0 {
+			return nil, fmt.Errorf("pop: list is empty")
+		}
+		last := l.Elems[len(l.Elems)-1]
+		l.Elems = l.Elems[:len(l.Elems)-1]
+		return last, nil
+	})
+	i.DefineNative("slice", 3, func(_ *Interpreter, args []Value) (Value, error) {
+		l, err := listArg("slice", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		start, err := intArg("slice", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		end, err := intArg("slice", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		// Negative bounds count from the end, as with indexing.
+		if start < 0 {
+			start += len(l.Elems)
+		}
+		if end < 0 {
+			end += len(l.Elems)
+		}
+		if start < 0 || end > len(l.Elems) || start > end {
+			return nil, fmt.Errorf("slice: range [%d, %d) out of bounds for length %d", start, end, len(l.Elems))
+		}
+		elems := make([]Value, end-start)
+		copy(elems, l.Elems[start:end])
+		return NewList(elems), nil
+	})
+	i.DefineNative("map", 2, func(interp *Interpreter, args []Value) (Value, error) {
+		l, err := listArg("map", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		elems := make([]Value, 0, len(l.Elems))
+		for _, e := range l.Elems {
+			v, err := interp.Call(args[1], e)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, v)
+		}
+		return NewList(elems), nil
+	})
+	i.DefineNative("filter", 2, func(interp *Interpreter, args []Value) (Value, error) {
+		l, err := listArg("filter", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		elems := make([]Value, 0)
+		for _, e := range l.Elems {
+			v, err := interp.Call(args[1], e)
+			if err != nil {
+				return nil, err
+			}
+			if truthy(v) {
+				elems = append(elems, e)
+			}
+		}
+		return NewList(elems), nil
+	})
+
+	// Maps.
+	i.DefineNative("keys", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		m, err := mapArg("keys", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewList(m.Keys()), nil
+	})
+	i.DefineNative("has", 2, func(_ *Interpreter, args []Value) (Value, error) {
+		m, err := mapArg("has", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		_, ok := m.Get(args[1])
+		return ok, nil
+	})
+	i.DefineNative("delete", 2, func(_ *Interpreter, args []Value) (Value, error) {
+		m, err := mapArg("delete", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return m.Delete(args[1]), nil
+	})
+
+	// Math.
+	i.DefineNative("sqrt", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		f, err := numberArg("sqrt", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return math.Sqrt(f), nil
+	})
+	i.DefineNative("floor", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		f, err := numberArg("floor", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return math.Floor(f), nil
+	})
+	i.DefineNative("pow", 2, func(_ *Interpreter, args []Value) (Value, error) {
+		x, err := numberArg("pow", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		y, err := numberArg("pow", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return math.Pow(x, y), nil
+	})
+	i.DefineNative("random", 0, func(interp *Interpreter, _ []Value) (Value, error) {
+		return interp.rand.Float64(), nil
+	})
+	i.DefineNative("seed", 1, func(interp *Interpreter, args []Value) (Value, error) {
+		n, err := intArg("seed", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		interp.rand.Seed(int64(n))
+		return nil, nil
+	})
+
+	// Types.
+	i.DefineNative("type", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		return typeName(args[0]), nil
+	})
+	i.DefineNative("str", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		return Stringify(args[0]), nil
+	})
+	i.DefineNative("num", 1, func(_ *Interpreter, args []Value) (Value, error) {
+		switch v := args[0].(type) {
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("num: can not convert %q to a number", v)
+			}
+			return f, nil
+		}
+		return nil, argError("num", 0, "a number or a string", args[0])
+	})
+}
@@ -0,0 +1,211 @@
+package lox
+
+type Stmt interface {
+	Accept(v StmtVisitor) (Value, error)
+
+	// Span is the source range the statement was parsed from.
+	Span() Span
+}
+
+type StmtVisitor interface {
+	visitBlockStmt(stmt *BlockStmt) (Value, error)
+	visitBreakStmt(stmt *BreakStmt) (Value, error)
+	visitClassStmt(stmt *ClassStmt) (Value, error)
+	visitContinueStmt(stmt *ContinueStmt) (Value, error)
+	visitExprStmt(stmt *ExprStmt) (Value, error)
+	visitFunStmt(stmt *FunStmt) (Value, error)
+	visitIfStmt(stmt *IfStmt) (Value, error)
+	visitPrintStmt(stmt *PrintStmt) (Value, error)
+	visitRetStmt(Return *RetStmt) (Value, error)
+	visitVarStmt(stmt *VarStmt) error
+	visitWhileStmt(stmt *WhileStmt) (Value, error)
+}
+
+// BlockStmt is a list of statements with their own scope. Blocks the
+// parser creates while desugaring have no braces.
+type BlockStmt struct {
+	LBrace Token
+	Stmts  []Stmt
+	RBrace Token
+}
+
+func (b *BlockStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitBlockStmt(b)
+}
+
+func (b *BlockStmt) Span() Span {
+	if b.LBrace.Span().IsValid() {
+		return spanOf(b.LBrace.Span(), b.RBrace.Span())
+	}
+	// Desugaring may put statements out of source order.
+	var span Span
+	for _, s := range b.Stmts {
+		sp := s.Span()
+		if !sp.IsValid() {
+			continue
+		}
+		if !span.IsValid() || sp.Start.Offset < span.Start.Offset {
+			span.Start = sp.Start
+		}
+		if !span.IsValid() || sp.End.Offset > span.End.Offset {
+			span.End = sp.End
+		}
+	}
+	return span
+}
+
+type BreakStmt struct {
+	Keyword   Token
+	Semicolon Token
+}
+
+func (b *BreakStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitBreakStmt(b)
+}
+
+func (b *BreakStmt) Span() Span {
+	return spanOf(b.Keyword.Span(), b.Semicolon.Span())
+}
+
+type ClassStmt struct {
+	Keyword    Token
+	Name       Token
+	Superclass *VarExpr
+	Methods    []*FunStmt
+	RBrace     Token
+}
+
+func (c *ClassStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitClassStmt(c)
+}
+
+func (c *ClassStmt) Span() Span {
+	return spanOf(c.Keyword.Span(), c.RBrace.Span())
+}
+
+type ContinueStmt struct {
+	Keyword   Token
+	Semicolon Token
+}
+
+func (c *ContinueStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitContinueStmt(c)
+}
+
+func (c *ContinueStmt) Span() Span {
+	return spanOf(c.Keyword.Span(), c.Semicolon.Span())
+}
+
+type ExprStmt struct {
+	Expr      Expr
+	Semicolon Token
+}
+
+func (e *ExprStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitExprStmt(e)
+
+}
+
+func (e *ExprStmt) Span() Span {
+	return spanOf(e.Expr.Span(), e.Semicolon.Span())
+}
+
+// FunStmt declares a function, or a method when Keyword is unset.
+type FunStmt struct {
+	Keyword Token
+	Name    Token
+	Params  []Token
+	Body    []Stmt
+	RBrace  Token
+}
+
+func (f *FunStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitFunStmt(f)
+
+}
+
+func (f *FunStmt) Span() Span {
+	return spanOf(spanOf(f.Keyword.Span(), f.Name.Span()), f.RBrace.Span())
+}
+
+type IfStmt struct {
+	Keyword Token
+	Cond    Expr
+	Then    Stmt
+	Else    Stmt
+}
+
+func (i *IfStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitIfStmt(i)
+}
+
+func (i *IfStmt) Span() Span {
+	if i.Else != nil {
+		return spanOf(i.Keyword.Span(), i.Else.Span())
+	}
+	return spanOf(i.Keyword.Span(), i.Then.Span())
+}
+
+type PrintStmt struct {
+	Keyword   Token
+	Expr      Expr
+	Semicolon Token
+}
+
+func (p *PrintStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitPrintStmt(p)
+
+}
+
+func (p *PrintStmt) Span() Span {
+	return spanOf(p.Keyword.Span(), p.Semicolon.Span())
+}
+
+type RetStmt struct {
+	Keyword   Token
+	Val       Expr
+	Semicolon Token
+}
+
+func (r *RetStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitRetStmt(r)
+}
+
+func (r *RetStmt) Span() Span {
+	return spanOf(r.Keyword.Span(), r.Semicolon.Span())
+}
+
+type VarStmt struct {
+	Keyword   Token
+	Name      Token
+	Init      Expr
+	Semicolon Token
+}
+
+func (vs *VarStmt) Accept(v StmtVisitor) (Value, error) {
+	return nil, v.visitVarStmt(vs)
+
+}
+
+func (vs *VarStmt) Span() Span {
+	return spanOf(vs.Keyword.Span(), vs.Semicolon.Span())
+}
+
+// WhileStmt is a while loop. For loops are desugared into one, in which
+// case Keyword is the "for" token and Incr, if any, is the increment
+// clause. Incr runs after every iteration, including those ended by
+// continue.
+type WhileStmt struct {
+	Keyword Token
+	Cond    Expr
+	Body    Stmt
+	Incr    Expr
+}
+
+func (w *WhileStmt) Accept(v StmtVisitor) (Value, error) {
+	return v.visitWhileStmt(w)
+}
+
+func (w *WhileStmt) Span() Span {
+	return spanOf(w.Keyword.Span(), w.Body.Span())
+}
@@ -0,0 +1,158 @@
+package lox
+
+import "fmt"
+
+type TokenType int
+
+type Token struct {
+	Type    TokenType
+	Lexeme  string
+	Literal Value
+	File    string
+	Line    int
+	Column  int
+
+	// Offset is the byte offset of the lexeme in the source.
+	Offset int
+}
+
+func (t *Token) String() string {
+	return fmt.Sprintf("%v %q %s", t.Type, t.Lexeme, t.Pos())
+}
+
+func (t TokenType) String() string {
+	if t >= 0 && int(t) < len(tokenTypeNames) {
+		return tokenTypeNames[t]
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+const (
+	// Single-character tokens.
+	LParen    TokenType = iota // 0
+	RParen                     // 1
+	LBrace                     // 2
+	RBrace                     // 3
+	LBracket                   // 4
+	RBracket                   // 5
+	Comma                      // 6
+	Colon                      // 7
+	Dot                        // 8
+	Minus                      // 9
+	Plus                       // 10
+	Semicolon                  // 11
+	Slash                      // 12
+	Star                       // 13
+
+	// One or two character tokens.
+	Bang         // 14
+	BangEqual    // 15
+	Equal        // 16
+	EqualEqual   // 17
+	Greater      // 18
+	GreaterEqual // 19
+	Less         // 20
+	LessEqual    // 21
+
+	// Literals.
+	Identifier // 22
+	String     // 23
+	Number     // 24
+
+	// Keywords.
+	And   // 25
+	Else  // 26
+	False // 27
+	Fun   // 28
+	For   // 29
+	If    // 30
+	Nil   // 31
+
+	Or       // 32
+	Print    // 33
+	Return   // 34
+	Super    // 35
+	True     // 36
+	Var      // 37
+	While    // 38
+	Class    // 39
+	This     // 40
+	Break    // 41
+	Continue // 42
+
+	// Trivia, kept by the lexer apart from the tokens it scans.
+	Comment // 43
+
+	// EOF ends every token stream.
+	EOF // 44
+)
+
+// tokenTypeNames names the token types after their constants.
+var tokenTypeNames = [...]string{
+	LParen:       "LParen",
+	RParen:       "RParen",
+	LBrace:       "LBrace",
+	RBrace:       "RBrace",
+	LBracket:     "LBracket",
+	RBracket:     "RBracket",
+	Comma:        "Comma",
+	Colon:        "Colon",
+	Dot:          "Dot",
+	Minus:        "Minus",
+	Plus:         "Plus",
+	Semicolon:    "Semicolon",
+	Slash:        "Slash",
+	Star:         "Star",
+	Bang:         "Bang",
+	BangEqual:    "BangEqual",
+	Equal:        "Equal",
+	EqualEqual:   "EqualEqual",
+	Greater:      "Greater",
+	GreaterEqual: "GreaterEqual",
+	Less:         "Less",
+	LessEqual:    "LessEqual",
+	Identifier:   "Identifier",
+	String:       "String",
+	Number:       "Number",
+	And:          "And",
+	Else:         "Else",
+	False:        "False",
+	Fun:          "Fun",
+	For:          "For",
+	If:           "If",
+	Nil:          "Nil",
+	Or:           "Or",
+	Print:        "Print",
+	Return:       "Return",
+	Super:        "Super",
+	True:         "True",
+	Var:          "Var",
+	While:        "While",
+	Class:        "Class",
+	This:         "This",
+	Break:        "Break",
+	Continue:     "Continue",
+	Comment:      "Comment",
+	EOF:          "EOF",
+}
+
+var Keywords = map[string]TokenType{
+	"and":      And,
+	"break":    Break,
+	"class":    Class,
+	"continue": Continue,
+	"else":     Else,
+	"false":    False,
+	"for":      For,
+	"fun":      Fun,
+	"if":       If,
+	"nil":      Nil,
+	"or":       Or,
+	"print":    Print,
+	"return":   Return,
+	"super":    Super,
+	"this":     This,
+	"true":     True,
+	"var":      Var,
+	"while":    While,
+}
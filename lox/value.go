@@ -0,0 +1,113 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value is a Lox runtime value. Its dynamic type is always one of:
+//
+//	nil        nil
+//	bool       true and false
+//	float64    numbers
+//	string     strings
+//	Callable   functions and classes
+//	*Instance  class instances
+//	*List      lists
+//	*Map       maps
+type Value = any
+
+// typeName returns the Lox name of v's type, for use in error messages.
+func typeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *LoxClass:
+		return "class"
+	case Callable:
+		return "function"
+	case *Instance:
+		return "instance"
+	case *List:
+		return "list"
+	case *Map:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Stringify formats v the way the print statement shows it.
+func Stringify(v Value) string {
+	switch j := v.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		// Very large and very small numbers are written with an
+		// exponent, which the lexer reads back.
+		if a := math.Abs(j); a >= 1e21 || a != 0 && a < 1e-6 {
+			return strconv.FormatFloat(j, 'g', -1, 64)
+		}
+		return strconv.FormatFloat(j, 'f', -1, 64)
+	case string:
+		return j
+	}
+	return fmt.Sprint(v)
+}
+
+// repr formats v for display inside a container, where strings are
+// quoted so that ["a, b"] and ["a", "b"] look different.
+func repr(v Value) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return Stringify(v)
+}
+
+// writeRepr writes repr(v) to b. The lists and maps in enclosing, those
+// being written around v, are shown as [...] and {...}, so that a list
+// holding itself prints as [[...]] rather than recursing forever.
+func writeRepr(b *strings.Builder, v Value, enclosing []Value) {
+	switch v := v.(type) {
+	case *List:
+		v.write(b, enclosing)
+	case *Map:
+		v.write(b, enclosing)
+	default:
+		b.WriteString(repr(v))
+	}
+}
+
+func contains(vs []Value, v Value) bool {
+	for _, w := range vs {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// truthy is true for everything but false and nil.
+func truthy(v Value) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+// equal compares by value for nil, bools, numbers and strings, and by
+// identity for everything else, so two lists or maps are only equal if
+// they are the same list or map.
+func equal(j, k Value) bool {
+	return j == k
+}
@@ -0,0 +1,525 @@
+package lox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// vm runs compiled functions on a value stack. It shares its globals, the
+// natives and the call trace with the Interpreter that owns it, so errors
+// look the same as under the tree-walker.
+type vm struct {
+	interp *Interpreter
+	stack  []Value
+	frames []vmFrame
+
+	// open are the upvalues still pointing at stack slots, ordered by
+	// slot.
+	open []*upvalue
+}
+
+type vmFrame struct {
+	cl *closure
+	ip int
+
+	// base is the stack index of the frame's slot 0.
+	base int
+
+	// traced is set when the call pushed a callFrame on the interpreter,
+	// which the frame pops when it returns. Frames entered from Go, the
+	// script itself and calls made through Interpreter.Call, are traced
+	// by their caller, if at all.
+	traced bool
+}
+
+// token returns a token positioned at the instruction at offset, for
+// errors and stack traces.
+func (f *vmFrame) token(offset int) Token {
+	line, col := f.cl.proto.chunk.position(offset)
+	return Token{File: f.cl.proto.file, Line: line, Column: col}
+}
+
+// closure is a compiled function together with the variables it captured.
+type closure struct {
+	proto    *funProto
+	upvalues []*upvalue
+}
+
+func (c *closure) Call(interp *Interpreter, args []Value) (Value, error) {
+	return interp.vm.call(c, c, args)
+}
+
+func (c *closure) Arity() int {
+	return c.proto.arity
+}
+
+func (c *closure) bind(inst *Instance) Callable {
+	return &boundMethod{receiver: inst, method: c}
+}
+
+func (c *closure) String() string {
+	return c.proto.String()
+}
+
+// boundMethod is a method looked up on an instance, which it is called on.
+type boundMethod struct {
+	receiver *Instance
+	method   *closure
+}
+
+func (b *boundMethod) Call(interp *Interpreter, args []Value) (Value, error) {
+	return interp.vm.call(b.method, b.receiver, args)
+}
+
+func (b *boundMethod) Arity() int {
+	return b.method.Arity()
+}
+
+func (b *boundMethod) String() string {
+	return b.method.String()
+}
+
+// upvalue is a variable captured by a closure. It refers to a stack slot
+// until the variable goes out of scope, and holds the value from then on.
+type upvalue struct {
+	slot   int
+	closed Value
+	isOpen bool
+}
+
+func (uv *upvalue) get(vm *vm) Value {
+	if uv.isOpen {
+		return vm.stack[uv.slot]
+	}
+	return uv.closed
+}
+
+func (uv *upvalue) set(vm *vm, v Value) {
+	if uv.isOpen {
+		vm.stack[uv.slot] = v
+	} else {
+		uv.closed = v
+	}
+}
+
+// exec runs a compiled script.
+func (vm *vm) exec(ctx context.Context, proto *funProto) (Value, error) {
+	defer vm.interp.begin(ctx)()
+	cl := &closure{proto: proto}
+	return vm.call(cl, cl, nil)
+}
+
+// call runs cl with slot0 as its receiver until it returns. It is
+// re-entered when a native calls back into a script function. On error
+// the stack is unwound to where it was.
+func (vm *vm) call(cl *closure, slot0 Value, args []Value) (Value, error) {
+	i := vm.interp
+	stackTop, frameTop, traceTop := len(vm.stack), len(vm.frames), len(i.frames)
+
+	vm.push(slot0)
+	vm.stack = append(vm.stack, args...)
+	vm.frames = append(vm.frames, vmFrame{cl: cl, base: stackTop})
+	v, err := vm.run(frameTop)
+	if err != nil {
+		vm.closeUpvalues(stackTop)
+		vm.stack = vm.stack[:stackTop]
+		vm.frames = vm.frames[:frameTop]
+		i.frames = i.frames[:traceTop]
+	}
+	return v, err
+}
+
+func (vm *vm) push(v Value) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *vm) pop() Value {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+func (vm *vm) peek(dist int) Value {
+	return vm.stack[len(vm.stack)-1-dist]
+}
+
+// fail turns err into a RuntimeError at the instruction at offset in the
+// innermost frame.
+func (vm *vm) fail(offset int, err error) error {
+	f := &vm.frames[len(vm.frames)-1]
+	return vm.interp.runtimeError(f.token(offset), "%v", err)
+}
+
+// run executes instructions until the frame at index base returns, and
+// returns its result.
+func (vm *vm) run(base int) (Value, error) {
+	i := vm.interp
+	for {
+		frame := &vm.frames[len(vm.frames)-1]
+		chunk := &frame.cl.proto.chunk
+		code := chunk.Code
+		start := frame.ip
+		op := Opcode(code[start])
+		frame.ip++
+
+		i.steps++
+		if i.maxSteps > 0 && i.steps > i.maxSteps {
+			return nil, i.limitError(frame.token(start), ErrStepLimit)
+		}
+
+		switch op {
+		case OpConstant:
+			vm.push(chunk.Constants[chunk.readShort(frame.ip)])
+			frame.ip += 2
+		case OpNil:
+			vm.push(nil)
+		case OpTrue:
+			vm.push(true)
+		case OpFalse:
+			vm.push(false)
+		case OpPop:
+			vm.pop()
+
+		case OpGetLocal:
+			vm.push(vm.stack[frame.base+int(code[frame.ip])])
+			frame.ip++
+		case OpSetLocal:
+			vm.stack[frame.base+int(code[frame.ip])] = vm.peek(0)
+			frame.ip++
+		case OpGetGlobal:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			v, ok := i.globals.vals[name]
+			if !ok {
+				return nil, vm.fail(start, fmt.Errorf("undefined variable '%s'", name))
+			}
+			vm.push(v)
+		case OpDefineGlobal:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			i.globals.Define(name, vm.pop())
+		case OpSetGlobal:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			if _, ok := i.globals.vals[name]; !ok {
+				return nil, vm.fail(start, fmt.Errorf("undefined variable '%s'", name))
+			}
+			i.globals.vals[name] = vm.peek(0)
+		case OpGetUpvalue:
+			vm.push(frame.cl.upvalues[code[frame.ip]].get(vm))
+			frame.ip++
+		case OpSetUpvalue:
+			frame.cl.upvalues[code[frame.ip]].set(vm, vm.peek(0))
+			frame.ip++
+
+		case OpGetProperty:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			v, err := getProperty(vm.pop(), name)
+			if err != nil {
+				return nil, vm.fail(start, err)
+			}
+			vm.push(v)
+		case OpSetProperty:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			val := vm.pop()
+			inst, err := fieldsOf(vm.pop())
+			if err != nil {
+				return nil, vm.fail(start, err)
+			}
+			inst.fields[name] = val
+			vm.push(val)
+		case OpGetSuper:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			superclass, ok := vm.pop().(*LoxClass)
+			this, ok2 := vm.pop().(*Instance)
+			if !ok || !ok2 {
+				return nil, vm.fail(start, errBadCode)
+			}
+			m, err := superMethod(superclass, this, name)
+			if err != nil {
+				return nil, vm.fail(start, err)
+			}
+			vm.push(m)
+
+		case OpEqual:
+			r := vm.pop()
+			vm.push(equal(vm.pop(), r))
+		case OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpSubtract, OpMultiply, OpDivide:
+			r := vm.pop()
+			l := vm.pop()
+			lf, rf, err := numbers(opSymbols[op], l, r)
+			if err != nil {
+				return nil, vm.fail(start, err)
+			}
+			vm.push(arith(op, lf, rf))
+		case OpAdd:
+			r := vm.pop()
+			v, err := add(vm.pop(), r)
+			if err != nil {
+				return nil, vm.fail(start, err)
+			}
+			vm.push(v)
+		case OpNot:
+			vm.push(!truthy(vm.pop()))
+		case OpNegate:
+			v, err := negate(vm.pop())
+			if err != nil {
+				return nil, vm.fail(start, err)
+			}
+			vm.push(v)
+
+		case OpPrint:
+			if _, err := fmt.Fprintln(i.stdout, Stringify(vm.pop())); err != nil {
+				return nil, vm.fail(start, fmt.Errorf("print: %v", err))
+			}
+
+		case OpJump:
+			frame.ip += 2 + chunk.readShort(frame.ip)
+		case OpJumpIfFalse:
+			dist := chunk.readShort(frame.ip)
+			frame.ip += 2
+			if !truthy(vm.peek(0)) {
+				frame.ip += dist
+			}
+		case OpLoop:
+			frame.ip -= chunk.readShort(frame.ip) - 2
+			if err := i.interrupted(frame.token(start)); err != nil {
+				return nil, err
+			}
+
+		case OpCall:
+			argc := int(code[frame.ip])
+			frame.ip++
+			if err := vm.callValue(vm.peek(argc), argc, frame.token(start)); err != nil {
+				return nil, err
+			}
+		case OpClosure:
+			proto := chunk.Constants[chunk.readShort(frame.ip)].(*funProto)
+			frame.ip += 2
+			cl := &closure{proto: proto, upvalues: make([]*upvalue, proto.upvalues)}
+			for j := range cl.upvalues {
+				isLocal, index := code[frame.ip], int(code[frame.ip+1])
+				frame.ip += 2
+				if isLocal == 1 {
+					cl.upvalues[j] = vm.capture(frame.base + index)
+				} else {
+					cl.upvalues[j] = frame.cl.upvalues[index]
+				}
+			}
+			vm.push(cl)
+		case OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.stack = vm.stack[:frame.base]
+			if frame.traced {
+				i.popFrame()
+			}
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == base {
+				return result, nil
+			}
+			vm.push(result)
+
+		case OpClass:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			vm.push(NewLoxClass(name, nil))
+		case OpInherit:
+			class, ok := vm.pop().(*LoxClass)
+			if !ok {
+				return nil, vm.fail(start, errBadCode)
+			}
+			superclass, ok := vm.peek(0).(*LoxClass)
+			if !ok {
+				return nil, vm.fail(start, fmt.Errorf("superclass must be a class"))
+			}
+			// The compiler never lets a class inherit from itself.
+			for c := superclass; c != nil; c = c.superclass {
+				if c == class {
+					return nil, vm.fail(start, errBadCode)
+				}
+			}
+			class.superclass = superclass
+		case OpMethod:
+			name := chunk.Constants[chunk.readShort(frame.ip)].(string)
+			frame.ip += 2
+			m, ok := vm.pop().(*closure)
+			class, ok2 := vm.peek(0).(*LoxClass)
+			if !ok || !ok2 {
+				return nil, vm.fail(start, errBadCode)
+			}
+			class.methods[name] = m
+
+		case OpList:
+			n := chunk.readShort(frame.ip)
+			frame.ip += 2
+			elems := make([]Value, n)
+			copy(elems, vm.stack[len(vm.stack)-n:])
+			vm.stack = vm.stack[:len(vm.stack)-n]
+			vm.push(NewList(elems))
+		case OpMap:
+			n := chunk.readShort(frame.ip)
+			frame.ip += 2
+			entries := vm.stack[len(vm.stack)-2*n:]
+			m := NewMap()
+			for j := 0; j < len(entries); j += 2 {
+				if err := m.Set(entries[j], entries[j+1]); err != nil {
+					return nil, vm.fail(start, err)
+				}
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*n]
+			vm.push(m)
+		case OpIndexGet:
+			index := vm.pop()
+			v, err := indexGet(vm.pop(), index)
+			if err != nil {
+				return nil, vm.fail(start, err)
+			}
+			vm.push(v)
+		case OpIndexSet:
+			val := vm.pop()
+			index := vm.pop()
+			if err := indexSet(vm.pop(), index, val); err != nil {
+				return nil, vm.fail(start, err)
+			}
+			vm.push(val)
+
+		default:
+			return nil, vm.fail(start, fmt.Errorf("unknown opcode %d", op))
+		}
+	}
+}
+
+// errBadCode is reported for instructions applied to values the compiler
+// never gives them, which only happens in tampered saved programs.
+var errBadCode = errors.New("malformed bytecode")
+
+// opSymbols names the operators of the VM's arithmetic and comparison
+// instructions in error messages.
+var opSymbols = map[Opcode]string{
+	OpGreater:      ">",
+	OpGreaterEqual: ">=",
+	OpLess:         "<",
+	OpLessEqual:    "<=",
+	OpSubtract:     "-",
+	OpMultiply:     "*",
+	OpDivide:       "/",
+}
+
+func arith(op Opcode, l, r float64) Value {
+	switch op {
+	case OpGreater:
+		return l > r
+	case OpGreaterEqual:
+		return l >= r
+	case OpLess:
+		return l < r
+	case OpLessEqual:
+		return l <= r
+	case OpSubtract:
+		return l - r
+	case OpMultiply:
+		return l * r
+	}
+	return l / r
+}
+
+// callValue calls callee, which is on the stack below its argc arguments.
+// Closures get a new frame that run continues with, anything else is
+// called right away and replaced by its result.
+func (vm *vm) callValue(callee Value, argc int, site Token) error {
+	i := vm.interp
+	switch fn := callee.(type) {
+	case *closure:
+		return vm.callClosure(fn, fn, argc, site)
+	case *boundMethod:
+		vm.stack[len(vm.stack)-argc-1] = fn.receiver
+		return vm.callClosure(fn.method, fn, argc, site)
+	case *LoxClass:
+		// Classes without an initializer, or declared by the tree-walker
+		// with a *Function for one, are called like any other callable.
+		if init, ok := fn.findMethod("init").(*closure); ok {
+			vm.stack[len(vm.stack)-argc-1] = NewInstance(fn)
+			return vm.callClosure(init, fn, argc, site)
+		}
+	}
+
+	fn, err := callable(callee)
+	if err != nil {
+		return i.runtimeError(site, "%v", err)
+	}
+	if err := checkArity(fn, argc); err != nil {
+		return i.runtimeError(site, "%v", err)
+	}
+	args := make([]Value, argc)
+	copy(args, vm.stack[len(vm.stack)-argc:])
+	if err := i.pushFrame(fn, site); err != nil {
+		return err
+	}
+	v, err := fn.Call(i, args)
+	i.popFrame()
+	if err != nil {
+		if _, ok := err.(*RuntimeError); !ok {
+			rerr := i.runtimeError(site, "%v", err)
+			rerr.Err = err
+			return rerr
+		}
+		return err
+	}
+	vm.stack = vm.stack[:len(vm.stack)-argc-1]
+	vm.push(v)
+	return nil
+}
+
+// callClosure pushes a frame for cl, whose receiver and arguments are on
+// the stack. named is what the call is called in stack traces.
+func (vm *vm) callClosure(cl *closure, named Callable, argc int, site Token) error {
+	i := vm.interp
+	if err := checkArity(cl, argc); err != nil {
+		return i.runtimeError(site, "%v", err)
+	}
+	if err := i.pushFrame(named, site); err != nil {
+		return err
+	}
+	vm.frames = append(vm.frames, vmFrame{
+		cl:     cl,
+		base:   len(vm.stack) - argc - 1,
+		traced: true,
+	})
+	return nil
+}
+
+// capture returns the upvalue for the stack slot, sharing it with other
+// closures that captured the same variable.
+func (vm *vm) capture(slot int) *upvalue {
+	j := len(vm.open)
+	for j > 0 && vm.open[j-1].slot >= slot {
+		if vm.open[j-1].slot == slot {
+			return vm.open[j-1]
+		}
+		j--
+	}
+	uv := &upvalue{slot: slot, isOpen: true}
+	vm.open = append(vm.open, nil)
+	copy(vm.open[j+1:], vm.open[j:])
+	vm.open[j] = uv
+	return uv
+}
+
+// closeUpvalues moves the variables in stack slots from up into their
+// upvalues, as those slots are about to be popped.
+func (vm *vm) closeUpvalues(from int) {
+	for len(vm.open) > 0 && vm.open[len(vm.open)-1].slot >= from {
+		uv := vm.open[len(vm.open)-1]
+		uv.closed = vm.stack[uv.slot]
+		uv.isOpen = false
+		vm.open = vm.open[:len(vm.open)-1]
+	}
+}
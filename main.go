@@ -1,47 +1,133 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
-	"log/slog"
 	"os"
+	"sort"
+
+	"glox/lox"
 )
 
-var fname = flag.String(
-	"read from file",
-	"",
-	"If filename is not given, you'll get a prompt",
+// Exit codes follow the sysexits.h convention used by the reference
+// Lox implementation.
+const (
+	exitOK      = 0
+	exitUsage   = 64 // bad command line
+	exitStatic  = 65 // lex, parse or resolve error, or a malformed compiled file
+	exitRuntime = 70 // error raised while executing the script
+	exitIOErr   = 74 // script could not be read
 )
 
 func main() {
-	fmt.Println("starting...")
+	if len(os.Args) > 1 {
+		if cmd, ok := commands()[os.Args[1]]; ok {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
 
-	foo := "./resources/sample-code/sample-state.txt"
-	fname = &foo
+	flag.Usage = usage
+	backendName := flag.String("backend", "tree", "execution backend, tree or vm")
+	flag.Parse()
 
-	fContent, err := openFile(*fname)
+	backend, err := lox.ParseBackend(*backendName)
 	if err != nil {
-		log.Fatal("read file: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	opt := lox.WithBackend(backend)
+	if flag.NArg() == 0 {
+		os.Exit(runPrompt(os.Stdin, opt))
 	}
-	lex := Lexer{
-		Source:  string(fContent),
-		Tokens:  []Token{},
-		line:    0,
-		start:   0,
-		current: 0,
+	os.Exit(runFile(flag.Arg(0), opt, lox.WithArgs(flag.Args()[1:])))
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-backend tree|vm] [script [args...]]\n", os.Args[0])
+	fmt.Fprintln(flag.CommandLine.Output(), "Without a script an interactive prompt is started.")
+	flag.PrintDefaults()
+	fmt.Fprintln(flag.CommandLine.Output(), "\nCommands:")
+	cmds := commands()
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
 	}
-	if err = lex.Scan(); err != nil {
-		log.Fatalf("lexer scan: %v", err)
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s %s\n    \t%s\n", name, cmds[name].args, cmds[name].desc)
 	}
+}
 
-	stmts := parse(lex.Tokens)
-	interp := NewInterpreter()
-	if err := interp.interpret(stmts); err != nil {
-		slog.Error("interpreter", "err", err)
+// runFile executes the script at path and returns the process exit code.
+// The script may also be a program saved by the compile command.
+func runFile(path string, opts ...lox.Option) int {
+	content, err := openFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read file: %v\n", err)
+		return exitIOErr
+	}
+	interp := lox.New(opts...)
+	if lox.IsProgram(content) {
+		prog, err := lox.ReadProgram(bytes.NewReader(content))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitStatic
+		}
+		_, err = interp.RunProgram(context.Background(), prog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCode(err)
+		}
+		return exitOK
 	}
-	slog.Info("complete")
+	if _, err := interp.RunFile(context.Background(), path, string(content)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// runPrompt reads and executes one line at a time. The same interpreter is
+// used for every line, so declarations survive between lines. Scripts
+// calling readLine() read from the same input as the prompt.
+func runPrompt(in io.Reader, opts ...lox.Option) int {
+	r := bufio.NewReader(in)
+	interp := lox.New(append(opts, lox.WithStdin(r))...)
+	for {
+		fmt.Print("> ")
+		line, err := r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			fmt.Fprintf(os.Stderr, "read prompt: %v\n", err)
+			return exitIOErr
+		}
+		if line == "" && err != nil {
+			break
+		}
+		v, rerr := interp.Run(context.Background(), line)
+		if rerr != nil {
+			fmt.Fprintln(os.Stderr, rerr)
+		} else if v != nil {
+			fmt.Println(lox.Stringify(v))
+		}
+		if err != nil {
+			break
+		}
+	}
+	fmt.Println()
+	return exitOK
+}
+
+func exitCode(err error) int {
+	var cerr *lox.CompileError
+	if errors.As(err, &cerr) {
+		return exitStatic
+	}
+	return exitRuntime
 }
 
 func openFile(fname string) ([]byte, error) {
@@ -53,12 +139,3 @@ func openFile(fname string) ([]byte, error) {
 	content, err := io.ReadAll(f)
 	return content, err
 }
-
-func parse(t []Token) []Stmt {
-	p := NewParser(t)
-	s, err := p.Parse()
-	if err != nil {
-		slog.Error("parse", "err", err)
-	}
-	return s
-}
@@ -1,5 +0,0 @@
-package main
-
-type FunRet struct {
-	Val any
-}